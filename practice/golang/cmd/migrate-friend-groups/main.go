@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"database/sql"
 	"errors"
 	"flag"
 	"log/slog"
@@ -12,6 +13,7 @@ import (
 	redis "github.com/redis/go-redis/v9"
 	mydb "github.com/stoneMan1982/workexperience/practice/golang/db"
 	"github.com/stoneMan1982/workexperience/practice/golang/pkg/config"
+	"github.com/stoneMan1982/workexperience/practice/golang/pkg/db/online"
 	"github.com/stoneMan1982/workexperience/practice/golang/pkg/logx"
 	"github.com/uptrace/bun"
 )
@@ -32,6 +34,11 @@ func main() {
 		redisDB           int
 		friendSeqKey      string
 		friendGroupSeqKey string
+		onlineMode        bool
+		chunkSize         int
+		throttle          time.Duration
+		maxLag            time.Duration
+		resume            bool
 	)
 
 	flag.StringVar(&cfgPath, "config", "../../config.yaml", "path to YAML config file")
@@ -43,6 +50,11 @@ func main() {
 	flag.IntVar(&redisDB, "redis-db", 0, "redis db index")
 	flag.StringVar(&friendSeqKey, "friend-seq-key", "FriendSeqKey", "redis key for friend version sequence")
 	flag.StringVar(&friendGroupSeqKey, "friend-group-seq-key", "FriendGroupSeqKey", "redis key for friend_group version sequence")
+	flag.BoolVar(&onlineMode, "online", false, "update friend.friend_group_id in resumable, throttled chunks instead of one statement")
+	flag.IntVar(&chunkSize, "chunk-size", 1000, "rows per chunk in --online mode")
+	flag.DurationVar(&throttle, "throttle", 0, "sleep between chunks in --online mode")
+	flag.DurationVar(&maxLag, "max-lag", time.Second, "pause chunking while replica lag exceeds this in --online mode")
+	flag.BoolVar(&resume, "resume", false, "resume a previous --online run from its saved cursor")
 	flag.Parse()
 
 	cfg, err := config.LoadConfig(cfgPath)
@@ -50,7 +62,10 @@ func main() {
 		slog.Error("load config failed", "path", cfgPath, "err", err)
 		os.Exit(1)
 	}
-	logx.Setup(cfg.Logging.Level, cfg.Logging.Format, cfg.Logging.AddSource)
+	if _, err := logx.SetupSinks(cfg.Logging.Level, cfg.Logging.Format, cfg.Logging.AddSource, cfg.Logging.BuildSinkSpecs()); err != nil {
+		slog.Error("setting up log sinks failed", "err", err)
+		os.Exit(1)
+	}
 
 	if !strings.EqualFold(cfg.Database.Dialect, "mysql") {
 		slog.Error("dialect must be mysql for this migration", "got", cfg.Database.Dialect)
@@ -74,17 +89,47 @@ func main() {
 		os.Exit(1)
 	}
 
-	if err := run(ctx, db, rdb, defaultName, dryRun, lockWaitSec, friendSeqKey, friendGroupSeqKey); err != nil {
+	opts := runOptions{
+		defaultName:       defaultName,
+		dryRun:            dryRun,
+		lockWaitSec:       lockWaitSec,
+		friendSeqKey:      friendSeqKey,
+		friendGroupSeqKey: friendGroupSeqKey,
+		online:            onlineMode,
+		chunkSize:         chunkSize,
+		throttle:          throttle,
+		maxLag:            maxLag,
+		resume:            resume,
+	}
+	if err := run(ctx, db, rdb, opts); err != nil {
 		slog.Error("migration failed", "err", err)
 		os.Exit(1)
 	}
 	slog.Info("migration finished")
 }
 
-func run(ctx context.Context, db *bun.DB, rdb *redis.Client, defaultName string, dryRun bool, lockWaitSec int, friendSeqKey, friendGroupSeqKey string) error {
+// runOptions bundles the migration's flags so the online and non-online
+// code paths in run() don't have to carry a long, duplicated parameter list.
+type runOptions struct {
+	defaultName       string
+	dryRun            bool
+	lockWaitSec       int
+	friendSeqKey      string
+	friendGroupSeqKey string
+
+	online    bool
+	chunkSize int
+	throttle  time.Duration
+	maxLag    time.Duration
+	resume    bool
+}
+
+func run(ctx context.Context, db *bun.DB, rdb *redis.Client, o runOptions) error {
+	defaultName, dryRun, lockWaitSec := o.defaultName, o.dryRun, o.lockWaitSec
+	friendSeqKey, friendGroupSeqKey := o.friendSeqKey, o.friendGroupSeqKey
 	// We want a rollback when dry-run; use a sentinel error to prevent commit.
 	var errDryRun = errors.New("dry-run rollback")
-	err := db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+	err := mydb.RunInTxWithRetry(ctx, db, &mydb.RunInTxOptions{RetryLockTimeout: lockWaitSec > 0}, func(ctx context.Context, tx bun.Tx) error {
 		if lockWaitSec > 0 {
 			if _, err := tx.ExecContext(ctx, "SET SESSION innodb_lock_wait_timeout = ?", lockWaitSec); err != nil {
 				return err
@@ -234,6 +279,14 @@ func run(ctx context.Context, db *bun.DB, rdb *redis.Client, defaultName string,
 			return errDryRun
 		}
 
+		if toUpdate > 0 && o.online {
+			// The chunked pass below needs its own transactions (and its own
+			// connections), so it can't see this tx's temporary tables; defer
+			// it until after this tx commits.
+			slog.Info("step3 deferred to online engine", "count", toUpdate)
+			return nil
+		}
+
 		// reserve per-row versions for friend updates
 		var startFriend int64
 		if toUpdate > 0 {
@@ -272,5 +325,100 @@ func run(ctx context.Context, db *bun.DB, rdb *redis.Client, defaultName string,
 		}
 		return err
 	}
+
+	if o.online {
+		return runOnlineFriendUpdate(ctx, db, rdb, o)
+	}
 	return nil
 }
+
+// runOnlineFriendUpdate re-runs step 3 (friend.friend_group_id assignment)
+// in --online mode: small, resumable, replication-lag-throttled chunks
+// keyed on friend.id instead of one table-locking UPDATE. It recomputes the
+// default/member lookups inline per chunk since chunks run in their own
+// transactions and can't share the outer tx's temporary tables.
+func runOnlineFriendUpdate(ctx context.Context, db *bun.DB, rdb *redis.Client, o runOptions) error {
+	var minID, maxID sql.NullInt64
+	if err := db.NewSelect().ColumnExpr("MIN(id)").ColumnExpr("MAX(id)").Table("friend").Scan(ctx, &minID, &maxID); err != nil {
+		return err
+	}
+	if !minID.Valid {
+		slog.Info("online: friend table is empty, nothing to do")
+		return nil
+	}
+
+	eng := online.NewEngine(db, rdb, "migrate_friend_groups_friend_update", "mysql", online.Options{
+		ChunkSize: o.chunkSize,
+		Throttle:  o.throttle,
+		MaxLag:    o.maxLag,
+		SeqKey:    o.friendSeqKey,
+		Resume:    o.resume,
+	})
+
+	chunkFn := func(ctx context.Context, tx bun.Tx, chunkMin, chunkMax int64, reserve online.ReserveFunc) (int64, error) {
+		var toUpdate int64
+		if err := tx.QueryRowContext(ctx, `
+			SELECT COUNT(*)
+			FROM friend f
+			JOIN (
+				SELECT fg.uid, MIN(fg.id) AS default_group_id
+				FROM friend_group fg
+				WHERE fg.is_default = 1 AND COALESCE(fg.is_deleted,0) = 0
+				GROUP BY fg.uid
+			) d ON d.uid = f.uid
+			LEFT JOIN (
+				SELECT fgm.uid, fgm.friend_uid, MIN(fgm.group_id) AS target_group_id
+				FROM friend_group_member fgm
+				JOIN friend_group fg ON fg.id = fgm.group_id
+				WHERE COALESCE(fgm.is_deleted,0) = 0 AND COALESCE(fg.is_deleted,0) = 0
+				GROUP BY fgm.uid, fgm.friend_uid
+			) m ON m.uid = f.uid AND m.friend_uid = f.to_uid
+			WHERE f.id BETWEEN ? AND ?
+			  AND COALESCE(f.is_deleted,0) = 0
+			  AND COALESCE(f.friend_group_id,0) <> COALESCE(m.target_group_id, d.default_group_id)
+		`, chunkMin, chunkMax).Scan(&toUpdate); err != nil {
+			return 0, err
+		}
+		if toUpdate == 0 {
+			return 0, nil
+		}
+
+		start, err := reserve(ctx, toUpdate)
+		if err != nil {
+			return 0, err
+		}
+
+		if _, err := tx.ExecContext(ctx, `
+			UPDATE friend f
+			JOIN (
+				SELECT f.id,
+					   COALESCE(m.target_group_id, d.default_group_id) AS new_gid,
+					   ROW_NUMBER() OVER (ORDER BY f.id) AS rn
+				FROM friend f
+				JOIN (
+					SELECT fg.uid, MIN(fg.id) AS default_group_id
+					FROM friend_group fg
+					WHERE fg.is_default = 1 AND COALESCE(fg.is_deleted,0) = 0
+					GROUP BY fg.uid
+				) d ON d.uid = f.uid
+				LEFT JOIN (
+					SELECT fgm.uid, fgm.friend_uid, MIN(fgm.group_id) AS target_group_id
+					FROM friend_group_member fgm
+					JOIN friend_group fg ON fg.id = fgm.group_id
+					WHERE COALESCE(fgm.is_deleted,0) = 0 AND COALESCE(fg.is_deleted,0) = 0
+					GROUP BY fgm.uid, fgm.friend_uid
+				) m ON m.uid = f.uid AND m.friend_uid = f.to_uid
+				WHERE f.id BETWEEN ? AND ?
+				  AND COALESCE(f.is_deleted,0) = 0
+				  AND COALESCE(f.friend_group_id,0) <> COALESCE(m.target_group_id, d.default_group_id)
+			) t ON t.id = f.id
+			SET f.friend_group_id = t.new_gid,
+				f.version = (? + t.rn - 1)
+		`, chunkMin, chunkMax, start); err != nil {
+			return 0, err
+		}
+		return toUpdate, nil
+	}
+
+	return eng.Run(ctx, minID.Int64, maxID.Int64, chunkFn)
+}
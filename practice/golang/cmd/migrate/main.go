@@ -0,0 +1,129 @@
+// Command migrate is the CLI entry point for the pkg/db/migrate framework.
+// It mirrors cmd/migrate-friend-groups in how it loads config and opens the
+// database, and replaces that command's hardcoded one-shot logic with the
+// versioned migration registry.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	redis "github.com/redis/go-redis/v9"
+	mydb "github.com/stoneMan1982/workexperience/practice/golang/db"
+	"github.com/stoneMan1982/workexperience/practice/golang/pkg/config"
+	"github.com/stoneMan1982/workexperience/practice/golang/pkg/db/migrate"
+	_ "github.com/stoneMan1982/workexperience/practice/golang/pkg/db/migrate/migrations"
+	"github.com/stoneMan1982/workexperience/practice/golang/pkg/logx"
+)
+
+func main() {
+	var (
+		cfgPath   string
+		dryRun    bool
+		redisAddr string
+		redisPass string
+		redisDB   int
+		steps     int
+	)
+
+	flag.StringVar(&cfgPath, "config", "../../config.yaml", "path to YAML config file")
+	flag.BoolVar(&dryRun, "dry-run", false, "wrap each migration's transaction in a rollback instead of committing")
+	flag.StringVar(&redisAddr, "redis-addr", "127.0.0.1:6379", "redis address host:port")
+	flag.StringVar(&redisPass, "redis-password", "", "redis password")
+	flag.IntVar(&redisDB, "redis-db", 0, "redis db index")
+	flag.IntVar(&steps, "steps", 0, "number of migrations to apply/revert (0 = all for up, 1 for down)")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: %s [flags] <up|down|redo|status|create NAME>\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	args := flag.Args()
+	if len(args) == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	cmd := args[0]
+
+	cfg, err := config.LoadConfig(cfgPath)
+	if err != nil {
+		slog.Error("load config failed", "path", cfgPath, "err", err)
+		os.Exit(1)
+	}
+	if _, err := logx.SetupSinks(cfg.Logging.Level, cfg.Logging.Format, cfg.Logging.AddSource, cfg.Logging.BuildSinkSpecs()); err != nil {
+		slog.Error("setting up log sinks failed", "err", err)
+		os.Exit(1)
+	}
+
+	if cmd == "create" {
+		if len(args) < 2 {
+			slog.Error("create requires a migration name")
+			os.Exit(2)
+		}
+		up, down, err := migrate.Create("pkg/db/migrate/migrations", args[1])
+		if err != nil {
+			slog.Error("create migration failed", "err", err)
+			os.Exit(1)
+		}
+		slog.Info("created migration files", "up", up, "down", down)
+		return
+	}
+
+	if err := migrate.Migrations.LoadSQLDir("pkg/db/migrate/migrations"); err != nil {
+		slog.Error("load sql migrations failed", "err", err)
+		os.Exit(1)
+	}
+
+	db, err := mydb.OpenFromConfig(&cfg.Database)
+	if err != nil {
+		slog.Error("open db failed", "err", err)
+		os.Exit(1)
+	}
+	defer mydb.Close(db)
+
+	rdb := redis.NewClient(&redis.Options{Addr: redisAddr, Password: redisPass, DB: redisDB})
+	defer rdb.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+	if err := rdb.Ping(ctx).Err(); err != nil {
+		slog.Error("redis ping failed", "addr", redisAddr, "err", err)
+		os.Exit(1)
+	}
+
+	m := migrate.NewMigrator(db, rdb, nil)
+	m.DryRun = dryRun
+
+	switch cmd {
+	case "up":
+		err = m.Up(ctx, steps)
+	case "down":
+		err = m.Down(ctx, steps)
+	case "redo":
+		err = m.Redo(ctx)
+	case "status":
+		var entries []migrate.StatusEntry
+		entries, err = m.Status(ctx)
+		if err == nil {
+			for _, e := range entries {
+				state := "pending"
+				if e.Applied {
+					state = "applied at " + e.AppliedAt.Format(time.RFC3339)
+				}
+				slog.Info("migration status", "version", e.Version, "name", e.Name, "state", state)
+			}
+		}
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		slog.Error("migrate command failed", "cmd", cmd, "err", err)
+		os.Exit(1)
+	}
+	slog.Info("migrate command finished", "cmd", cmd)
+}
@@ -2,12 +2,14 @@ package main
 
 import (
 	"context"
-	"encoding/json"
 	"flag"
 	"fmt"
 	"log"
+	"net/url"
 	"os"
 	"os/signal"
+	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
@@ -15,122 +17,144 @@ import (
 	rs "github.com/stoneMan1982/workexperience/practice/golang/pkg/rs"
 )
 
+// newUniversalClient builds the redis.UniversalClient for rawURL, falling
+// back to a plain standalone client against addr/password/db when rawURL is
+// empty. It understands the same redis:// and rediss:// schemes go-redis's
+// own ParseURL does, plus two schemes this binary adds for HA deployments:
+// redis-sentinel://master/db?addrs=host1:26379,host2:26379 builds a
+// Sentinel-backed FailoverClient, and redis-cluster://host1:6379,host2:6379
+// builds a ClusterClient. Every stream key this package touches is a single
+// "streamBase:shard" string, so under Cluster it always hashes to one slot;
+// nothing here needs cross-slot commands.
+func newUniversalClient(rawURL, addr, password string, db int) (redis.UniversalClient, error) {
+	if rawURL == "" {
+		return redis.NewClient(&redis.Options{Addr: addr, Password: password, DB: db}), nil
+	}
+
+	scheme, rest, ok := strings.Cut(rawURL, "://")
+	if !ok {
+		return nil, fmt.Errorf("redis-url %q: missing scheme", rawURL)
+	}
+
+	switch scheme {
+	case "redis", "rediss":
+		opts, err := redis.ParseURL(rawURL)
+		if err != nil {
+			return nil, fmt.Errorf("parse redis-url: %w", err)
+		}
+		return redis.NewClient(opts), nil
+
+	case "redis-sentinel":
+		u, err := url.Parse("redis-sentinel://" + rest)
+		if err != nil {
+			return nil, fmt.Errorf("parse redis-url: %w", err)
+		}
+		addrs := strings.Split(u.Query().Get("addrs"), ",")
+		dbIndex := db
+		if p := strings.TrimPrefix(u.Path, "/"); p != "" {
+			n, err := strconv.Atoi(p)
+			if err != nil {
+				return nil, fmt.Errorf("redis-url: sentinel db %q: %w", p, err)
+			}
+			dbIndex = n
+		}
+		return redis.NewFailoverClient(&redis.FailoverOptions{
+			MasterName:    u.Host,
+			SentinelAddrs: addrs,
+			Password:      password,
+			DB:            dbIndex,
+		}), nil
+
+	case "redis-cluster":
+		addrs := strings.Split(strings.TrimSuffix(rest, "/"), ",")
+		return redis.NewClusterClient(&redis.ClusterOptions{
+			Addrs:    addrs,
+			Password: password,
+		}), nil
+
+	default:
+		return nil, fmt.Errorf("redis-url %q: unsupported scheme %q", rawURL, scheme)
+	}
+}
+
 func main() {
 	var (
-		redisAddr  string
-		redisPass  string
-		redisDB    int
-		streamBase string
-		shard      int
-		nodeID     int
-		totalNodes int
-		group      string
-		consumer   string
-		blockMs    int
-		batch      int64
+		redisAddr   string
+		redisURL    string
+		redisPass   string
+		redisDB     int
+		streamBase  string
+		totalShards int
+		group       string
+		peerID      string
+		blockMs     int
+		batch       int64
+		heartbeatMs int
+		memberTTLMs int
+		rebalanceMs int
 	)
 
-	flag.StringVar(&redisAddr, "redis-addr", "127.0.0.1:6379", "redis address host:port")
-	flag.StringVar(&redisPass, "redis-password", "", "redis password")
+	flag.StringVar(&redisAddr, "redis-addr", "127.0.0.1:6379", "redis address host:port (ignored if -redis-url is set)")
+	flag.StringVar(&redisURL, "redis-url", "", "redis connection URL: redis://, rediss://, redis-sentinel://master/db?addrs=host1:26379,host2:26379, or redis-cluster://host1:6379,host2:6379; overrides -redis-addr")
+	flag.StringVar(&redisPass, "redis-password", "", "redis password (master/cluster password for -redis-url sentinel/cluster schemes)")
 	flag.IntVar(&redisDB, "redis-db", 0, "redis db index")
 	flag.StringVar(&streamBase, "stream", "mystream", "stream base name")
-	flag.IntVar(&shard, "shard", -1, "shard index to consume (e.g., 0..2); if <0, derive from node-id")
-	flag.IntVar(&nodeID, "node-id", 1, "this node id (1..N)")
-	flag.IntVar(&totalNodes, "total-nodes", 3, "cluster total nodes; used for validation")
-	flag.StringVar(&group, "group", "g", "consumer group name")
-	flag.StringVar(&consumer, "consumer", "", "consumer name (default: hostname-pid)")
+	flag.IntVar(&totalShards, "total-shards", 3, "total number of stream shards to divide across live peers")
+	flag.StringVar(&group, "group", "g", "consumer group name; also namespaces cluster membership")
+	flag.StringVar(&peerID, "peer-id", "", "this node's membership/consumer id (default: hostname-pid)")
 	flag.IntVar(&blockMs, "block-ms", 5000, "XREADGROUP block timeout in ms")
 	flag.Int64Var(&batch, "batch", 100, "max messages per read")
+	flag.IntVar(&heartbeatMs, "heartbeat-ms", 0, "membership heartbeat interval in ms (default: member-ttl-ms/3)")
+	flag.IntVar(&memberTTLMs, "member-ttl-ms", 15000, "how long a missed heartbeat is tolerated before peers drop this node")
+	flag.IntVar(&rebalanceMs, "rebalance-ms", 5000, "how often to recompute owned shards from current membership")
 	flag.Parse()
 
-	if consumer == "" {
+	if peerID == "" {
 		h, _ := os.Hostname()
-		consumer = fmt.Sprintf("%s-%d", h, os.Getpid())
+		peerID = fmt.Sprintf("%s-%d", h, os.Getpid())
 	}
 
 	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer cancel()
 
-	rdb := redis.NewClient(&redis.Options{Addr: redisAddr, Password: redisPass, DB: redisDB})
+	rdb, err := newUniversalClient(redisURL, redisAddr, redisPass, redisDB)
+	if err != nil {
+		log.Fatalf("redis-url: %v", err)
+	}
 	if err := rdb.Ping(ctx).Err(); err != nil {
 		log.Fatalf("redis ping failed: %v", err)
 	}
 
-	// determine shard from node-id (node-id starts from 1)
-	if shard < 0 {
-		if nodeID < 1 {
-			log.Fatalf("invalid node-id: %d (must be >= 1)", nodeID)
-		}
-		shard = nodeID - 1
-	}
-	if totalNodes > 0 {
-		if nodeID < 1 || nodeID > totalNodes {
-			log.Fatalf("invalid node-id: %d (must be in 1..%d)", nodeID, totalNodes)
-		}
-		if shard < 0 || shard >= totalNodes {
-			log.Fatalf("invalid derived shard: %d (total-nodes=%d)", shard, totalNodes)
-		}
-	}
-
-	c := rs.NewShardedConsumer(rdb, streamBase, shard, group, consumer,
-		rs.WithBatch(batch),
-		rs.WithBlock(time.Duration(blockMs)*time.Millisecond),
-	)
-
-	log.Printf("consumer started: stream=%s:%d group=%s consumer=%s", streamBase, shard, group, consumer)
-	_ = c.Run(ctx, func(ctx context.Context, msg redis.XMessage) error {
-		return handleMessage(ctx, rdb, fmt.Sprintf("%s:%d", streamBase, shard), group, msg)
+	registry := rs.NewHandlerRegistry(nil).WithFallback(func(ctx context.Context, msg redis.XMessage) error {
+		log.Printf("processing id=%s values=%v", msg.ID, msg.Values)
+		return nil
 	})
-}
-
-func handleMessage(ctx context.Context, rdb *redis.Client, stream, group string, msg redis.XMessage) error {
-	// Determine message kind
-	var kind string
-	if v, ok := msg.Values["kind"]; ok {
-		switch t := v.(type) {
-		case string:
-			kind = t
-		case []byte:
-			kind = string(t)
-		default:
-			kind = fmt.Sprintf("%v", v)
+	rs.RegisterHandler(registry, "BatchMemberReadTask", func(ctx context.Context, msg redis.XMessage, b *rs.BatchMemberReadTask) error {
+		// Process each task in the batch. Replace with real logic.
+		log.Printf("batch received: id=%s batch_id=%s tasks=%d", msg.ID, b.ID, len(b.Tasks))
+		for i, t := range b.Tasks {
+			log.Printf("  task[%d]: id=%s message_id=%d channel_id=%s channel_type=%d uid=%s from_uid=%s login_uid=%s req_channel_id=%s req_channel_type=%d message_seq=%d message_id_str=%s",
+				i, t.ID, t.MessageID, t.ChannelID, t.ChannelType, t.UID, t.FromUID, t.LoginUID, t.ReqChannelID, t.ReqChannelType, t.MessageSeq, t.MessageIDStr)
 		}
-	}
-
-	if kind != "BatchMemberReadTask" {
-		// Fallback: log and ack other kinds
-		log.Printf("processing stream=%s id=%s kind=%s values=%v", stream, msg.ID, kind, msg.Values)
 		return nil
-	}
+	})
 
-	// Extract payload
-	var raw []byte
-	if v, ok := msg.Values["payload"]; ok {
-		switch t := v.(type) {
-		case string:
-			raw = []byte(t)
-		case []byte:
-			raw = t
-		default:
-			// Unknown type; keep pending for inspection
-			return fmt.Errorf("unexpected payload type %T", v)
-		}
-	} else {
-		return fmt.Errorf("missing payload field")
+	coordOpts := []rs.CoordinatorOption{
+		rs.WithMemberTTL(time.Duration(memberTTLMs) * time.Millisecond),
+		rs.WithRebalanceEvery(time.Duration(rebalanceMs) * time.Millisecond),
+		rs.WithConsumerOptions(
+			rs.WithBatch(batch),
+			rs.WithBlock(time.Duration(blockMs)*time.Millisecond),
+		),
 	}
-
-	// Decode batch payload
-	var batchMsg rs.BatchMemberReadTask
-	if err := json.Unmarshal(raw, &batchMsg); err != nil {
-		return fmt.Errorf("unmarshal BatchMemberReadTask failed: %w", err)
+	if heartbeatMs > 0 {
+		coordOpts = append(coordOpts, rs.WithHeartbeatEvery(time.Duration(heartbeatMs)*time.Millisecond))
 	}
 
-	// Process each task in the batch. Replace with real logic.
-	log.Printf("batch received: stream=%s id=%s batch_id=%s tasks=%d", stream, msg.ID, batchMsg.ID, len(batchMsg.Tasks))
-	for i, t := range batchMsg.Tasks {
-		log.Printf("  task[%d]: id=%s message_id=%d channel_id=%s channel_type=%d uid=%s from_uid=%s login_uid=%s req_channel_id=%s req_channel_type=%d message_seq=%d message_id_str=%s",
-			i, t.ID, t.MessageID, t.ChannelID, t.ChannelType, t.UID, t.FromUID, t.LoginUID, t.ReqChannelID, t.ReqChannelType, t.MessageSeq, t.MessageIDStr)
-	}
+	coord := rs.NewCoordinator(rdb, streamBase, totalShards, group, peerID, registry.Handler(), coordOpts...)
 
-	return nil
+	log.Printf("consumer started: stream=%s total-shards=%d group=%s peer-id=%s", streamBase, totalShards, group, peerID)
+	if err := coord.Run(ctx); err != nil && ctx.Err() == nil {
+		log.Printf("coordinator stopped: %v", err)
+	}
 }
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/stoneMan1982/workexperience/practice/golang/pkg/db/migrate"
+	_ "github.com/stoneMan1982/workexperience/practice/golang/pkg/db/migrate/migrations"
+	"github.com/stoneMan1982/workexperience/practice/golang/pkg/health"
+	cli "github.com/urfave/cli/v2"
+)
+
+// serveCommand blocks until SIGINT/SIGTERM, keeping the DB and redis
+// connections setupAppState opened alive. It stands in for whatever
+// long-running service this binary ends up hosting.
+var serveCommand = &cli.Command{
+	Name:  "serve",
+	Usage: "run the long-lived service until a termination signal is received",
+	Action: func(ctx *cli.Context) error {
+		st := stateFrom(ctx)
+		sigCtx, cancel := signal.NotifyContext(ctx.Context, syscall.SIGINT, syscall.SIGTERM)
+		defer cancel()
+
+		slog.Info("serve: ready",
+			"dialect", st.cfg.Database.Dialect,
+			"db", st.cfg.Database.DBName,
+		)
+		<-sigCtx.Done()
+		slog.Info("serve: shutting down")
+		return nil
+	},
+}
+
+// migrateCommand exposes the pkg/db/migrate framework (up/down/redo/status)
+// through the shared app wiring instead of the standalone cmd/migrate
+// binary's own config/db/redis setup.
+var migrateCommand = &cli.Command{
+	Name:      "migrate",
+	Usage:     "run schema migrations",
+	ArgsUsage: "<up|down|redo|status>",
+	Flags: []cli.Flag{
+		&cli.IntFlag{Name: "steps", Value: 0, Usage: "number of migrations to apply/revert (0 = all for up, 1 for down)"},
+		&cli.BoolFlag{Name: "dry-run", Usage: "wrap each migration's transaction in a rollback instead of committing"},
+	},
+	Action: func(ctx *cli.Context) error {
+		st := stateFrom(ctx)
+		sub := ctx.Args().First()
+		if sub == "" {
+			return fmt.Errorf("migrate requires a subcommand: up|down|redo|status")
+		}
+
+		m := migrate.NewMigrator(st.db, st.rdb, nil)
+		m.DryRun = ctx.Bool("dry-run")
+
+		switch sub {
+		case "up":
+			return m.Up(ctx.Context, ctx.Int("steps"))
+		case "down":
+			return m.Down(ctx.Context, ctx.Int("steps"))
+		case "redo":
+			return m.Redo(ctx.Context)
+		case "status":
+			entries, err := m.Status(ctx.Context)
+			if err != nil {
+				return err
+			}
+			for _, e := range entries {
+				state := "pending"
+				if e.Applied {
+					state = "applied at " + e.AppliedAt.Format(time.RFC3339)
+				}
+				slog.Info("migration status", "version", e.Version, "name", e.Name, "state", state)
+			}
+			return nil
+		default:
+			return fmt.Errorf("unknown migrate subcommand %q", sub)
+		}
+	},
+}
+
+// dbCommand groups DB-related operator actions.
+var dbCommand = &cli.Command{
+	Name:  "db",
+	Usage: "database operations",
+	Subcommands: []*cli.Command{
+		{
+			Name:  "ping",
+			Usage: "ping the configured database",
+			Action: func(ctx *cli.Context) error {
+				st := stateFrom(ctx)
+				if err := st.db.PingContext(ctx.Context); err != nil {
+					return fmt.Errorf("db ping: %w", err)
+				}
+				slog.Info("db ping ok", "dialect", st.cfg.Database.Dialect, "db", st.cfg.Database.DBName)
+				return nil
+			},
+		},
+	},
+}
+
+// redisCommand groups redis-related operator actions.
+var redisCommand = &cli.Command{
+	Name:  "redis",
+	Usage: "redis operations",
+	Subcommands: []*cli.Command{
+		{
+			Name:  "ping",
+			Usage: "ping the configured redis instance",
+			Action: func(ctx *cli.Context) error {
+				st := stateFrom(ctx)
+				if err := st.rdb.Ping(ctx.Context).Err(); err != nil {
+					return fmt.Errorf("redis ping: %w", err)
+				}
+				slog.Info("redis ping ok", "addr", ctx.String("redis-addr"))
+				return nil
+			},
+		},
+	},
+}
+
+// healthcheckCommand runs every registered health.Checker and prints a
+// structured JSON report to stdout, exiting non-zero if any probe failed.
+// It's meant to be wired into a container orchestrator's readiness/liveness
+// probe.
+var healthcheckCommand = &cli.Command{
+	Name:  "healthcheck",
+	Usage: "run readiness probes and report their status as JSON",
+	Action: func(ctx *cli.Context) error {
+		st := stateFrom(ctx)
+
+		reg := health.NewRegistry()
+		reg.Register(health.DBChecker("database", st.db))
+		reg.Register(health.RedisChecker("redis", st.rdb))
+
+		checkCtx, cancel := context.WithTimeout(ctx.Context, 5*time.Second)
+		defer cancel()
+
+		ok, results := reg.Run(checkCtx)
+
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(struct {
+			OK      bool            `json:"ok"`
+			Results []health.Result `json:"results"`
+		}{OK: ok, Results: results}); err != nil {
+			return fmt.Errorf("encode healthcheck report: %w", err)
+		}
+
+		if !ok {
+			return cli.Exit("one or more health checks failed", 1)
+		}
+		return nil
+	},
+}
@@ -0,0 +1,93 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/stoneMan1982/workexperience/practice/golang/pkg/retry"
+	"github.com/uptrace/bun"
+)
+
+// RunInTxOptions configures RunInTxWithRetry.
+type RunInTxOptions struct {
+	// TxOptions is passed through to bun.DB.RunInTx unchanged.
+	TxOptions *sql.TxOptions
+	// MaxAttempts caps the number of attempts (including the first); default 5.
+	MaxAttempts int
+	// BaseBackoff/CapBackoff bound the exponential-jitter retry delay;
+	// defaults are 20ms and 2s.
+	BaseBackoff time.Duration
+	CapBackoff  time.Duration
+	// RetryLockTimeout additionally retries MySQL 1205 (lock wait timeout),
+	// which by default is treated as a non-retryable, caller-visible error.
+	RetryLockTimeout bool
+	// Classifier overrides the default SQLSTATE/MySQL-error classifier.
+	Classifier retry.Classifier
+}
+
+func (o *RunInTxOptions) normalize() {
+	if o.MaxAttempts <= 0 {
+		o.MaxAttempts = 5
+	}
+	if o.BaseBackoff <= 0 {
+		o.BaseBackoff = 20 * time.Millisecond
+	}
+	if o.CapBackoff <= 0 {
+		o.CapBackoff = 2 * time.Second
+	}
+	if o.Classifier == nil {
+		o.Classifier = retry.NewClassifier(o.RetryLockTimeout)
+	}
+}
+
+// RunInTxWithRetry runs fn inside a bun transaction, retrying with
+// exponential-jitter backoff when the error is classified as a transient
+// deadlock / lock-wait / serialization failure. It aborts immediately on
+// context cancellation or any non-retryable error.
+func RunInTxWithRetry(ctx context.Context, bdb *bun.DB, opts *RunInTxOptions, fn func(ctx context.Context, tx bun.Tx) error) error {
+	o := RunInTxOptions{}
+	if opts != nil {
+		o = *opts
+	}
+	o.normalize()
+
+	start := time.Now()
+	var lastErr error
+	for attempt := 0; attempt < o.MaxAttempts; attempt++ {
+		lastErr = bdb.RunInTx(ctx, o.TxOptions, fn)
+		if lastErr == nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return lastErr
+		}
+		code, retryable := o.Classifier.Classify(lastErr)
+		if !retryable {
+			return lastErr
+		}
+		if attempt == o.MaxAttempts-1 {
+			break
+		}
+
+		delay := retry.Backoff(attempt, o.BaseBackoff, o.CapBackoff)
+		slog.Warn("retrying transaction after retryable error",
+			"attempt", attempt+1,
+			"max_attempts", o.MaxAttempts,
+			"elapsed", time.Since(start),
+			"code", string(code),
+			"delay", delay,
+		)
+
+		t := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+	return fmt.Errorf("db: giving up after %d attempts: %w", o.MaxAttempts, lastErr)
+}
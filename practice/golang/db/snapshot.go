@@ -0,0 +1,49 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/uptrace/bun"
+)
+
+// TxReadOnlySnapshot is the sql.TxOptions used for a read-only snapshot
+// transaction; exported so callers that want to open the transaction
+// themselves (e.g. to compose with other helpers) can reuse it directly.
+var TxReadOnlySnapshot = sql.TxOptions{
+	ReadOnly:  true,
+	Isolation: sql.LevelRepeatableRead,
+}
+
+// RunInReadOnlySnapshotTx runs fn inside a read-only transaction that takes
+// a stable snapshot of the database for its whole duration, so a count
+// query and a later read within fn can't observe rows written in between.
+//
+// Postgres additionally issues SET TRANSACTION ... READ ONLY, DEFERRABLE so
+// the backend takes a true serializable snapshot; MySQL issues START
+// TRANSACTION WITH CONSISTENT SNAPSHOT to pin the read view. SQLite has no
+// equivalent snapshot isolation, so it falls back to a plain read-only
+// transaction there.
+func RunInReadOnlyTx(ctx context.Context, bdb *bun.DB, fn func(ctx context.Context, tx bun.Tx) error) error {
+	dialect := strings.ToLower(bdb.Dialect().Name().String())
+	if dialect == "sqlite" {
+		return bdb.RunInTx(ctx, &sql.TxOptions{ReadOnly: true}, fn)
+	}
+
+	return bdb.RunInTx(ctx, &TxReadOnlySnapshot, func(ctx context.Context, tx bun.Tx) error {
+		switch dialect {
+		case "pg":
+			if _, err := tx.ExecContext(ctx,
+				"SET TRANSACTION ISOLATION LEVEL REPEATABLE READ, READ ONLY, DEFERRABLE"); err != nil {
+				return fmt.Errorf("pin postgres snapshot: %w", err)
+			}
+		case "mysql":
+			if _, err := tx.ExecContext(ctx, "START TRANSACTION WITH CONSISTENT SNAPSHOT"); err != nil {
+				return fmt.Errorf("pin mysql snapshot: %w", err)
+			}
+		}
+		return fn(ctx, tx)
+	})
+}
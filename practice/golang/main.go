@@ -1,49 +1,107 @@
+// Command workexperience is the module's main entry point. It exposes
+// serve/migrate/db/redis/healthcheck subcommands through a urfave/cli/v2
+// app so container orchestrators (and operators) have one binary with a
+// consistent --config/--mode wiring instead of a hand-rolled demo.
 package main
 
 import (
 	"fmt"
 	"log/slog"
 	"os"
+	"strings"
 
+	redis "github.com/redis/go-redis/v9"
+	mydb "github.com/stoneMan1982/workexperience/practice/golang/db"
 	"github.com/stoneMan1982/workexperience/practice/golang/pkg/config"
 	"github.com/stoneMan1982/workexperience/practice/golang/pkg/logx"
+	cli "github.com/urfave/cli/v2"
+	"github.com/uptrace/bun"
 )
 
+// appState is what the shared Before hook stores on cli.Context so every
+// subcommand sees the same config/logger/DB/redis wiring.
+type appState struct {
+	cfg *config.Config
+	db  *bun.DB
+	rdb *redis.Client
+}
+
+const appStateKey = "appState"
+
+func stateFrom(ctx *cli.Context) *appState {
+	return ctx.App.Metadata[appStateKey].(*appState)
+}
+
 func main() {
+	app := &cli.App{
+		Name:  "workexperience",
+		Usage: "practice/golang service entry point",
+		Flags: []cli.Flag{
+			&cli.StringFlag{Name: "config", Value: "./config.yaml", Usage: "path to YAML config file", EnvVars: []string{"APP_CONFIG"}},
+			&cli.StringFlag{Name: "mode", Value: "production", Usage: "development|production; development forces add_source=true and format=text"},
+			&cli.StringFlag{Name: "redis-addr", Value: "127.0.0.1:6379", Usage: "redis address host:port", EnvVars: []string{"REDIS_ADDR"}},
+			&cli.StringFlag{Name: "redis-password", Value: "", Usage: "redis password", EnvVars: []string{"REDIS_PASSWORD"}},
+			&cli.IntFlag{Name: "redis-db", Value: 0, Usage: "redis db index"},
+		},
+		Before: setupAppState,
+		After:  teardownAppState,
+		Commands: []*cli.Command{
+			serveCommand,
+			migrateCommand,
+			dbCommand,
+			redisCommand,
+			healthcheckCommand,
+		},
+	}
 
-	var permission uint32 = 234749951
-	const bitCreateGroupPerm = 5
-	mask := uint32(1) << bitCreateGroupPerm
+	if err := app.Run(os.Args); err != nil {
+		slog.Error("command failed", "err", err)
+		os.Exit(1)
+	}
+}
 
-	hasCreateGroupPerm := (permission & mask) != 0
-	fmt.Println("CreateGroupPerm:", hasCreateGroupPerm) // true
-	fmt.Println("Bit value:", (permission>>bitCreateGroupPerm)&1)
+// setupAppState is the shared Before hook: it loads config, initializes
+// logx, opens the DB, and builds the redis client, then stores all of it
+// on the cli.Context (via App.Metadata) so every subcommand reuses the
+// same initialization instead of repeating it.
+func setupAppState(ctx *cli.Context) error {
+	cfg, err := config.LoadConfig(ctx.String("config"))
+	if err != nil {
+		return fmt.Errorf("load config: %w", err)
+	}
+
+	if mode := strings.ToLower(strings.TrimSpace(ctx.String("mode"))); mode == "development" {
+		cfg.Logging.AddSource = true
+		cfg.Logging.Format = "text"
+	}
+
+	if _, err := logx.SetupSinks(cfg.Logging.Level, cfg.Logging.Format, cfg.Logging.AddSource, cfg.Logging.BuildSinkSpecs()); err != nil {
+		return fmt.Errorf("setting up log sinks: %w", err)
+	}
 
-	cfg, err := config.LoadConfig("./config.yaml")
+	db, err := mydb.OpenFromConfig(&cfg.Database)
 	if err != nil {
-		logx.Setup("info", "json", false)
-		slog.Error("load config failed", "err", err)
-		return
+		return fmt.Errorf("open db: %w", err)
 	}
 
-	logx.Setup(cfg.Logging.Level, cfg.Logging.Format, cfg.Logging.AddSource)
-
-	slog.Info("config loaded",
-		"dialect", cfg.Database.Dialect,
-		"host", cfg.Database.Host,
-		"db", cfg.Database.DBName,
-		"log_level", cfg.Logging.Level,
-		"log_format", cfg.Logging.Format,
-	)
-
-	// Demo: different levels; only error/fatal should include source (when add_source=true).
-	slog.Debug("debug message")
-	slog.Info("info message")
-	slog.Warn("warn message")
-	slog.Error("error message with source")
-
-	// Demo: fatal (opt-in via env to avoid always exiting during development)
-	if os.Getenv("DEMO_FATAL") == "1" {
-		logx.Fatal("fatal demo: exiting process now", "hint", "unset DEMO_FATAL to skip")
+	rdb := redis.NewClient(&redis.Options{
+		Addr:     ctx.String("redis-addr"),
+		Password: ctx.String("redis-password"),
+		DB:       ctx.Int("redis-db"),
+	})
+
+	ctx.App.Metadata[appStateKey] = &appState{cfg: cfg, db: db, rdb: rdb}
+	return nil
+}
+
+// teardownAppState closes the resources setupAppState opened.
+func teardownAppState(ctx *cli.Context) error {
+	raw, ok := ctx.App.Metadata[appStateKey]
+	if !ok {
+		return nil
 	}
+	st := raw.(*appState)
+	_ = st.rdb.Close()
+	_ = mydb.Close(st.db)
+	return nil
 }
@@ -9,6 +9,8 @@ import (
 
 	"github.com/go-playground/validator/v10"
 	"gopkg.in/yaml.v3"
+
+	"github.com/stoneMan1982/workexperience/practice/golang/pkg/logx"
 )
 
 type Config struct {
@@ -62,6 +64,82 @@ type LoggingConfig struct {
 	Format string `yaml:"format" validate:"omitempty,oneof=json text"`
 	// AddSource: include source location in logs
 	AddSource bool `yaml:"add_source"`
+	// Sinks optionally fans log output out to multiple destinations, each
+	// gated by its own MinLevel (e.g. INFO+ to stdout, ERROR+ additionally
+	// to syslog and a remote HTTP collector). If empty, logging falls back
+	// to a single stdout writer using Level/Format/AddSource above.
+	Sinks []SinkConfig `yaml:"sinks" validate:"dive"`
+}
+
+// SinkConfig declares one fanout destination for LoggingConfig.Sinks.
+type SinkConfig struct {
+	Type     string `yaml:"type" validate:"required,oneof=stdout file syslog http"`
+	MinLevel string `yaml:"min_level" validate:"omitempty,oneof=debug info warn error fatal"`
+
+	File   *FileSinkConfig   `yaml:"file,omitempty"`
+	Syslog *SyslogSinkConfig `yaml:"syslog,omitempty"`
+	HTTP   *HTTPSinkConfig   `yaml:"http,omitempty"`
+}
+
+// FileSinkConfig configures SinkConfig.Type == "file".
+type FileSinkConfig struct {
+	Path         string   `yaml:"path" validate:"required"`
+	MaxSizeBytes int64    `yaml:"max_size_bytes" validate:"gte=0"`
+	MaxAge       Duration `yaml:"max_age" validate:"gte=0"`
+	MaxBackups   int      `yaml:"max_backups" validate:"gte=0"`
+}
+
+// SyslogSinkConfig configures SinkConfig.Type == "syslog" (RFC 5424).
+type SyslogSinkConfig struct {
+	Network string `yaml:"network" validate:"required,oneof=udp tcp unix"`
+	Addr    string `yaml:"addr" validate:"required"`
+	Tag     string `yaml:"tag"`
+}
+
+// HTTPSinkConfig configures SinkConfig.Type == "http".
+type HTTPSinkConfig struct {
+	URL           string   `yaml:"url" validate:"required,url"`
+	QueueSize     int      `yaml:"queue_size" validate:"gte=0"`
+	BatchSize     int      `yaml:"batch_size" validate:"gte=0"`
+	FlushInterval Duration `yaml:"flush_interval" validate:"gte=0"`
+}
+
+// BuildSinkSpecs translates the declarative Sinks list into logx.SinkSpec
+// values ready for logx.SetupSinks. Returns nil if no sinks are configured,
+// so callers can fall back to plain Level/Format/AddSource logging.
+func (lc LoggingConfig) BuildSinkSpecs() []logx.SinkSpec {
+	if len(lc.Sinks) == 0 {
+		return nil
+	}
+	specs := make([]logx.SinkSpec, 0, len(lc.Sinks))
+	for _, sc := range lc.Sinks {
+		spec := logx.SinkSpec{Type: sc.Type, MinLevel: sc.MinLevel}
+		if sc.File != nil {
+			spec.File = &logx.FileSinkSpec{
+				Path:         sc.File.Path,
+				MaxSizeBytes: sc.File.MaxSizeBytes,
+				MaxAge:       time.Duration(sc.File.MaxAge),
+				MaxBackups:   sc.File.MaxBackups,
+			}
+		}
+		if sc.Syslog != nil {
+			spec.Syslog = &logx.SyslogSinkSpec{
+				Network: sc.Syslog.Network,
+				Addr:    sc.Syslog.Addr,
+				Tag:     sc.Syslog.Tag,
+			}
+		}
+		if sc.HTTP != nil {
+			spec.HTTP = &logx.HTTPSinkSpec{
+				URL:           sc.HTTP.URL,
+				QueueSize:     sc.HTTP.QueueSize,
+				BatchSize:     sc.HTTP.BatchSize,
+				FlushInterval: time.Duration(sc.HTTP.FlushInterval),
+			}
+		}
+		specs = append(specs, spec)
+	}
+	return specs
 }
 
 func LoadConfig(configFile string) (*Config, error) {
@@ -0,0 +1,63 @@
+package migrate
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var createNameRe = regexp.MustCompile(`[^a-z0-9_]+`)
+
+// Create scaffolds a new pair of NNNN_name.up.sql / NNNN_name.down.sql files
+// under dir, using the next version after the highest one already present.
+// It returns the paths of the two created files.
+func Create(dir, name string) (upPath, downPath string, err error) {
+	next, err := nextVersion(dir)
+	if err != nil {
+		return "", "", err
+	}
+	slug := createNameRe.ReplaceAllString(strings.ToLower(strings.TrimSpace(name)), "_")
+	slug = strings.Trim(slug, "_")
+	if slug == "" {
+		return "", "", fmt.Errorf("migrate: create requires a non-empty name")
+	}
+
+	base := fmt.Sprintf("%04d_%s", next, slug)
+	upPath = filepath.Join(dir, base+".up.sql")
+	downPath = filepath.Join(dir, base+".down.sql")
+
+	header := fmt.Sprintf("-- migration: %s\n-- created: %s\n\n", base, time.Now().UTC().Format(time.RFC3339))
+	if err := os.WriteFile(upPath, []byte(header), 0o644); err != nil {
+		return "", "", fmt.Errorf("write up file: %w", err)
+	}
+	if err := os.WriteFile(downPath, []byte(header), 0o644); err != nil {
+		return "", "", fmt.Errorf("write down file: %w", err)
+	}
+	return upPath, downPath, nil
+}
+
+func nextVersion(dir string) (int64, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 1, nil
+		}
+		return 0, fmt.Errorf("read migrations dir: %w", err)
+	}
+	var max int64
+	versionRe := regexp.MustCompile(`^(\d{4,})_`)
+	for _, e := range entries {
+		m := versionRe.FindStringSubmatch(e.Name())
+		if m == nil {
+			continue
+		}
+		var v int64
+		if _, err := fmt.Sscanf(m[1], "%d", &v); err == nil && v > max {
+			max = v
+		}
+	}
+	return max + 1, nil
+}
@@ -0,0 +1,426 @@
+// Package migrate implements a small goose-inspired migration framework on
+// top of bun: migrations are registered Go functions (or, equivalently,
+// paired NNNN_name.up.sql / NNNN_name.down.sql files read via the same
+// Migration shape) keyed by a monotonic integer version, and applied or
+// rolled back inside a transaction whose outcome is recorded in the
+// bun_migrations table.
+package migrate
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	redis "github.com/redis/go-redis/v9"
+	"github.com/uptrace/bun"
+)
+
+// MigrationFunc runs one direction of a migration inside tx.
+type MigrationFunc func(ctx context.Context, tx bun.Tx) error
+
+// ReservedRange records a half-open range of sequence values a migration
+// claimed from an external source (today: Redis IncrBy) before mutating
+// rows. It is persisted alongside the bun_migrations row so a crash
+// mid-apply leaves evidence of what was already reserved instead of the
+// next run silently reserving (and leaking) another range.
+type ReservedRange struct {
+	Key   string
+	Start int64
+	End   int64
+}
+
+// Migration is one registered schema change.
+type Migration struct {
+	Version int64
+	Name    string
+	Up      MigrationFunc
+	Down    MigrationFunc
+
+	// PreApply runs before Up, inside the same transaction. It is the place
+	// for a migration that needs externally-reserved version ranges (e.g.
+	// the friend_group sequence in Redis) to claim them once the affected
+	// row count is known, and before any UPDATE runs. Its return value is
+	// seeded into PostApply's reserved slice and persisted on the
+	// bun_migrations row.
+	PreApply func(ctx context.Context, tx bun.Tx, rdb *redis.Client) (*ReservedRange, error)
+	// PostApply runs after Up succeeds, inside the same transaction. reserved
+	// holds whatever PreApply returned (empty if PreApply is unset); a
+	// migration whose Up makes its own reservations (e.g. the friend
+	// sequence, whose size isn't known until Up's own inserts have run) must
+	// append them and return the full slice here, since that return value -
+	// not PreApply's - is what gets persisted on the bun_migrations row.
+	// Returning reserved unchanged is fine when PostApply has nothing to add.
+	PostApply func(ctx context.Context, tx bun.Tx, rdb *redis.Client, reserved []ReservedRange) ([]ReservedRange, error)
+}
+
+// migrationRecord mirrors the bun_migrations table.
+type migrationRecord struct {
+	bun.BaseModel `bun:"table:bun_migrations,alias:bm"`
+
+	Version   int64     `bun:",pk"`
+	Name      string    `bun:",notnull"`
+	AppliedAt time.Time `bun:",notnull"`
+	// Reserved records every ReservedRange claimed while applying this
+	// migration (from PreApply, from PostApply, or both), so a crash
+	// mid-apply leaves evidence of what was already reserved instead of the
+	// next run silently reserving (and leaking) another range.
+	Reserved []ReservedRange `bun:",nullzero,type:json"`
+}
+
+// Registry holds the set of known migrations, keyed by version.
+type Registry struct {
+	byVersion map[int64]*Migration
+}
+
+// NewRegistry returns an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{byVersion: make(map[int64]*Migration)}
+}
+
+// Register adds a migration. It panics on a duplicate version since that is
+// always a programmer error (e.g. a Create-generated file whose version
+// was never bumped before merging).
+func (r *Registry) Register(m *Migration) {
+	if _, ok := r.byVersion[m.Version]; ok {
+		panic(fmt.Sprintf("migrate: version %d already registered", m.Version))
+	}
+	r.byVersion[m.Version] = m
+}
+
+var sqlFileRe = regexp.MustCompile(`^(\d{4,})_(.+)\.(up|down)\.sql$`)
+
+// LoadSQLDir registers every NNNN_name.up.sql / NNNN_name.down.sql pair
+// found in dir as a Migration, so a migration authored via Create (which
+// only ever scaffolds these file pairs, never a Go function) actually runs
+// instead of sitting inert. Each file's contents are executed verbatim via
+// tx.ExecContext, so it must be a single statement (or a batch the driver
+// itself accepts unsplit) -- exactly what bun's ExecContext passes through
+// for a Go-function migration doing the same thing by hand. A .down.sql is
+// optional; a migration with no down file has no Down (matching Migrator's
+// existing "migration has no Down" error on Migrator.Down/Redo).
+func (r *Registry) LoadSQLDir(dir string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("migrate: read sql migrations dir: %w", err)
+	}
+
+	type pair struct {
+		version  int64
+		name     string
+		up, down string
+	}
+	bases := map[string]*pair{}
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		m := sqlFileRe.FindStringSubmatch(e.Name())
+		if m == nil {
+			continue
+		}
+		version, err := strconv.ParseInt(m[1], 10, 64)
+		if err != nil {
+			return fmt.Errorf("migrate: parse version from %q: %w", e.Name(), err)
+		}
+		base := m[1] + "_" + m[2]
+		p := bases[base]
+		if p == nil {
+			p = &pair{version: version, name: m[2]}
+			bases[base] = p
+		}
+		full := filepath.Join(dir, e.Name())
+		if m[3] == "up" {
+			p.up = full
+		} else {
+			p.down = full
+		}
+	}
+
+	for base, p := range bases {
+		if p.up == "" {
+			return fmt.Errorf("migrate: %s has a .down.sql with no matching .up.sql", base)
+		}
+		mig := &Migration{Version: p.version, Name: p.name, Up: sqlFileMigrationFunc(p.up)}
+		if p.down != "" {
+			mig.Down = sqlFileMigrationFunc(p.down)
+		}
+		r.Register(mig)
+	}
+	return nil
+}
+
+// sqlFileMigrationFunc returns a MigrationFunc that runs path's contents
+// inside tx, stripping "-- "-prefixed line comments (e.g. Create's own
+// header) first so a migration nobody filled in yet is a harmless no-op
+// instead of an empty-statement error.
+func sqlFileMigrationFunc(path string) MigrationFunc {
+	return func(ctx context.Context, tx bun.Tx) error {
+		body, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("migrate: read %s: %w", path, err)
+		}
+		stmt := stripSQLLineComments(string(body))
+		if stmt == "" {
+			return nil
+		}
+		if _, err := tx.ExecContext(ctx, stmt); err != nil {
+			return fmt.Errorf("migrate: exec %s: %w", path, err)
+		}
+		return nil
+	}
+}
+
+// stripSQLLineComments drops every "-- ..." line and trims the result.
+func stripSQLLineComments(sqlText string) string {
+	lines := strings.Split(sqlText, "\n")
+	kept := lines[:0]
+	for _, line := range lines {
+		if strings.HasPrefix(strings.TrimSpace(line), "--") {
+			continue
+		}
+		kept = append(kept, line)
+	}
+	return strings.TrimSpace(strings.Join(kept, "\n"))
+}
+
+func (r *Registry) sorted() []*Migration {
+	out := make([]*Migration, 0, len(r.byVersion))
+	for _, m := range r.byVersion {
+		out = append(out, m)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Version < out[j].Version })
+	return out
+}
+
+// Migrations is the package-level registry migrations register themselves
+// into via Migrations.Register, mirroring goose's global registration.
+var Migrations = NewRegistry()
+
+// Migrator applies/rolls back migrations from a Registry against DB,
+// recording progress in the bun_migrations table.
+type Migrator struct {
+	DB       *bun.DB
+	Redis    *redis.Client
+	Registry *Registry
+	// DryRun wraps every applied migration's transaction in a rollback, so
+	// the SQL runs (and can be logged) without any row ever persisting.
+	DryRun bool
+}
+
+// NewMigrator builds a Migrator. reg may be nil to use the package-level
+// Migrations registry.
+func NewMigrator(db *bun.DB, rdb *redis.Client, reg *Registry) *Migrator {
+	if reg == nil {
+		reg = Migrations
+	}
+	return &Migrator{DB: db, Redis: rdb, Registry: reg}
+}
+
+func (m *Migrator) ensureTable(ctx context.Context) error {
+	_, err := m.DB.NewCreateTable().Model((*migrationRecord)(nil)).IfNotExists().Exec(ctx)
+	return err
+}
+
+func (m *Migrator) records(ctx context.Context) ([]migrationRecord, error) {
+	if err := m.ensureTable(ctx); err != nil {
+		return nil, err
+	}
+	var recs []migrationRecord
+	if err := m.DB.NewSelect().Model(&recs).Order("version ASC").Scan(ctx); err != nil {
+		return nil, err
+	}
+	return recs, nil
+}
+
+// StatusEntry describes one migration's state for the status command.
+type StatusEntry struct {
+	Version   int64
+	Name      string
+	Applied   bool
+	AppliedAt time.Time
+}
+
+// Status reports every registered migration and whether it has been applied.
+func (m *Migrator) Status(ctx context.Context) ([]StatusEntry, error) {
+	recs, err := m.records(ctx)
+	if err != nil {
+		return nil, err
+	}
+	appliedAt := make(map[int64]time.Time, len(recs))
+	for _, r := range recs {
+		appliedAt[r.Version] = r.AppliedAt
+	}
+	out := make([]StatusEntry, 0, len(m.Registry.byVersion))
+	for _, mig := range m.Registry.sorted() {
+		at, ok := appliedAt[mig.Version]
+		out = append(out, StatusEntry{Version: mig.Version, Name: mig.Name, Applied: ok, AppliedAt: at})
+	}
+	return out, nil
+}
+
+// Up applies up to n pending migrations in ascending version order.
+// n<=0 applies every pending migration.
+func (m *Migrator) Up(ctx context.Context, n int) error {
+	recs, err := m.records(ctx)
+	if err != nil {
+		return err
+	}
+	applied := make(map[int64]bool, len(recs))
+	for _, r := range recs {
+		applied[r.Version] = true
+	}
+
+	done := 0
+	for _, mig := range m.Registry.sorted() {
+		if applied[mig.Version] {
+			continue
+		}
+		if n > 0 && done >= n {
+			break
+		}
+		if err := m.applyUp(ctx, mig); err != nil {
+			return fmt.Errorf("migrate up %04d_%s: %w", mig.Version, mig.Name, err)
+		}
+		done++
+	}
+	return nil
+}
+
+// Down rolls back up to n applied migrations in descending version order.
+// n<=0 is treated as 1, matching goose's "down one step" default.
+func (m *Migrator) Down(ctx context.Context, n int) error {
+	if n <= 0 {
+		n = 1
+	}
+	recs, err := m.records(ctx)
+	if err != nil {
+		return err
+	}
+	for i := len(recs) - 1; i >= 0 && n > 0; i-- {
+		mig, ok := m.Registry.byVersion[recs[i].Version]
+		if !ok {
+			return fmt.Errorf("migrate down: version %d is applied but not registered in this build", recs[i].Version)
+		}
+		if err := m.applyDown(ctx, mig); err != nil {
+			return fmt.Errorf("migrate down %04d_%s: %w", mig.Version, mig.Name, err)
+		}
+		n--
+	}
+	return nil
+}
+
+// Redo rolls back and re-applies the most recently applied migration.
+func (m *Migrator) Redo(ctx context.Context) error {
+	recs, err := m.records(ctx)
+	if err != nil {
+		return err
+	}
+	if len(recs) == 0 {
+		return fmt.Errorf("migrate redo: no migrations applied")
+	}
+	last := recs[len(recs)-1]
+	mig, ok := m.Registry.byVersion[last.Version]
+	if !ok {
+		return fmt.Errorf("migrate redo: version %d is applied but not registered in this build", last.Version)
+	}
+	if err := m.applyDown(ctx, mig); err != nil {
+		return fmt.Errorf("migrate redo (down) %04d_%s: %w", mig.Version, mig.Name, err)
+	}
+	if err := m.applyUp(ctx, mig); err != nil {
+		return fmt.Errorf("migrate redo (up) %04d_%s: %w", mig.Version, mig.Name, err)
+	}
+	return nil
+}
+
+var errDryRunRollback = fmt.Errorf("migrate: dry-run rollback")
+
+func (m *Migrator) applyUp(ctx context.Context, mig *Migration) error {
+	start := time.Now()
+	slog.Info("applying migration", "version", mig.Version, "name", mig.Name, "dry_run", m.DryRun)
+
+	err := m.DB.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		var reserved []ReservedRange
+		if mig.PreApply != nil {
+			r, err := mig.PreApply(ctx, tx, m.Redis)
+			if err != nil {
+				return fmt.Errorf("pre-apply: %w", err)
+			}
+			if r != nil {
+				reserved = append(reserved, *r)
+			}
+		}
+		if mig.Up != nil {
+			if err := mig.Up(ctx, tx); err != nil {
+				return fmt.Errorf("up: %w", err)
+			}
+		}
+		if mig.PostApply != nil {
+			r, err := mig.PostApply(ctx, tx, m.Redis, reserved)
+			if err != nil {
+				return fmt.Errorf("post-apply: %w", err)
+			}
+			reserved = r
+		}
+
+		rec := &migrationRecord{Version: mig.Version, Name: mig.Name, AppliedAt: time.Now(), Reserved: reserved}
+		if _, err := tx.NewInsert().Model(rec).Exec(ctx); err != nil {
+			return fmt.Errorf("record bun_migrations row: %w", err)
+		}
+
+		if m.DryRun {
+			return errDryRunRollback
+		}
+		return nil
+	})
+	if err == errDryRunRollback {
+		slog.Info("dry-run migration rolled back", "version", mig.Version, "name", mig.Name, "elapsed", time.Since(start))
+		return nil
+	}
+	if err != nil {
+		slog.Info("migration failed", "version", mig.Version, "name", mig.Name, "err", err.Error())
+		return err
+	}
+	slog.Info("migration applied", "version", mig.Version, "name", mig.Name, "elapsed", time.Since(start))
+	return nil
+}
+
+func (m *Migrator) applyDown(ctx context.Context, mig *Migration) error {
+	if mig.Down == nil {
+		return fmt.Errorf("migration %04d_%s has no Down", mig.Version, mig.Name)
+	}
+	start := time.Now()
+	slog.Info("reverting migration", "version", mig.Version, "name", mig.Name, "dry_run", m.DryRun)
+
+	err := m.DB.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		if err := mig.Down(ctx, tx); err != nil {
+			return fmt.Errorf("down: %w", err)
+		}
+		if _, err := tx.NewDelete().Model((*migrationRecord)(nil)).Where("version = ?", mig.Version).Exec(ctx); err != nil {
+			return fmt.Errorf("remove bun_migrations row: %w", err)
+		}
+		if m.DryRun {
+			return errDryRunRollback
+		}
+		return nil
+	})
+	if err == errDryRunRollback {
+		slog.Info("dry-run revert rolled back", "version", mig.Version, "name", mig.Name, "elapsed", time.Since(start))
+		return nil
+	}
+	if err != nil {
+		slog.Info("revert failed", "version", mig.Version, "name", mig.Name, "err", err.Error())
+		return err
+	}
+	slog.Info("migration reverted", "version", mig.Version, "name", mig.Name, "elapsed", time.Since(start))
+	return nil
+}
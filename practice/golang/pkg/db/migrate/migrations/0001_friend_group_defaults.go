@@ -0,0 +1,265 @@
+// Package migrations holds the Go-function migrations registered into
+// migrate.Migrations. Each file is one migration; the version in the
+// filename must match the Version field registered in init().
+package migrations
+
+import (
+	"context"
+	"fmt"
+
+	redis "github.com/redis/go-redis/v9"
+	"github.com/stoneMan1982/workexperience/practice/golang/pkg/db/migrate"
+	"github.com/uptrace/bun"
+)
+
+const (
+	defaultFriendGroupName = "我的好友"
+	friendSeqKey           = "FriendSeqKey"
+	friendGroupSeqKey      = "FriendGroupSeqKey"
+)
+
+// friendGroupDefaultsState threads counts computed in Up through to
+// PreApply (which reserves the version ranges those counts need) and back
+// to Up (which consumes the reservations), since all three hooks run
+// inside the same migration transaction.
+type friendGroupDefaultsState struct {
+	rdb *redis.Client
+
+	missingGroups  int
+	existingGroups int
+	friendUpdates  int
+
+	startMissingGroup  int64
+	startExistingGroup int64
+	startFriend        int64
+
+	// friendReserved records the friend.version range Up claimed via
+	// IncrBy, once it knows st.friendUpdates, so PostApply can add it to
+	// what gets persisted on the bun_migrations row alongside reserve's
+	// friend_group range.
+	friendReserved *migrate.ReservedRange
+}
+
+func init() {
+	st := &friendGroupDefaultsState{}
+
+	migrate.Migrations.Register(&migrate.Migration{
+		Version: 1,
+		Name:    "friend_group_defaults",
+		Up:      st.up,
+		Down:    st.down,
+		PreApply: func(ctx context.Context, tx bun.Tx, rdb *redis.Client) (*migrate.ReservedRange, error) {
+			return st.reserve(ctx, tx, rdb)
+		},
+		PostApply: func(ctx context.Context, tx bun.Tx, rdb *redis.Client, reserved []migrate.ReservedRange) ([]migrate.ReservedRange, error) {
+			if st.friendReserved != nil {
+				reserved = append(reserved, *st.friendReserved)
+			}
+			return reserved, nil
+		},
+	})
+}
+
+// up reproduces the one-shot logic that used to live inline in
+// cmd/migrate-friend-groups/main.go: every user gets a default friend_group,
+// and every friend row points at either its explicit group or that default.
+func (st *friendGroupDefaultsState) up(ctx context.Context, tx bun.Tx) error {
+	if _, err := tx.ExecContext(ctx, `DROP TEMPORARY TABLE IF EXISTS tmp_fg_existing`); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `
+		CREATE TEMPORARY TABLE tmp_fg_existing (PRIMARY KEY(id))
+		AS
+		SELECT fg.id
+		FROM friend_group fg
+		WHERE fg.name = ?
+	`, defaultFriendGroupName); err != nil {
+		return err
+	}
+	if err := tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM tmp_fg_existing`).Scan(&st.existingGroups); err != nil {
+		return err
+	}
+	if err := tx.QueryRowContext(ctx, `
+		SELECT COUNT(*)
+		FROM `+"`user`"+` u
+		LEFT JOIN friend_group fg ON fg.uid = u.uid AND fg.name = ?
+		WHERE fg.id IS NULL
+	`, defaultFriendGroupName).Scan(&st.missingGroups); err != nil {
+		return err
+	}
+
+	if st.missingGroups > 0 {
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO friend_group (uid, name, is_default, is_deleted, version)
+			SELECT t.uid, ?, 1, 0, (? + t.rn - 1)
+			FROM (
+				SELECT u.uid, ROW_NUMBER() OVER (ORDER BY u.uid) AS rn
+				FROM `+"`user`"+` u
+				LEFT JOIN friend_group fg ON fg.uid = u.uid AND fg.name = ?
+				WHERE fg.id IS NULL
+			) AS t
+		`, defaultFriendGroupName, st.startMissingGroup, defaultFriendGroupName); err != nil {
+			return err
+		}
+	}
+
+	if st.existingGroups > 0 {
+		if _, err := tx.ExecContext(ctx, `
+			UPDATE friend_group fg
+			JOIN (
+				SELECT e.id, ROW_NUMBER() OVER (ORDER BY e.id) AS rn
+				FROM tmp_fg_existing e
+			) AS t ON t.id = fg.id
+			SET fg.is_default = 1,
+				fg.is_deleted = 0,
+				fg.version = (? + t.rn - 1)
+		`, st.startExistingGroup); err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, `DROP TEMPORARY TABLE IF EXISTS tmp_defaults`); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `
+		CREATE TEMPORARY TABLE tmp_defaults
+		(PRIMARY KEY(uid))
+		AS
+		SELECT fg.uid, MIN(fg.id) AS default_group_id
+		FROM friend_group fg
+		WHERE fg.is_default = 1 AND COALESCE(fg.is_deleted,0) = 0
+		GROUP BY fg.uid
+	`); err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, `DROP TEMPORARY TABLE IF EXISTS tmp_member`); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `
+		CREATE TEMPORARY TABLE tmp_member
+		(PRIMARY KEY(uid, friend_uid))
+		AS
+		SELECT fgm.uid, fgm.friend_uid, MIN(fgm.group_id) AS target_group_id
+		FROM friend_group_member fgm
+		JOIN friend_group fg ON fg.id = fgm.group_id
+		WHERE COALESCE(fgm.is_deleted,0) = 0
+		  AND COALESCE(fg.is_deleted,0) = 0
+		GROUP BY fgm.uid, fgm.friend_uid
+	`); err != nil {
+		return err
+	}
+
+	if err := tx.QueryRowContext(ctx, `
+		SELECT COUNT(*) AS cnt
+		FROM friend f
+		JOIN tmp_defaults d ON d.uid = f.uid
+		LEFT JOIN tmp_member m ON m.uid = f.uid AND m.friend_uid = f.to_uid
+		WHERE COALESCE(f.is_deleted,0) = 0
+		  AND COALESCE(f.friend_group_id,0) <> COALESCE(m.target_group_id, d.default_group_id)
+	`).Scan(&st.friendUpdates); err != nil {
+		return err
+	}
+
+	if st.friendUpdates > 0 {
+		// The friend row set (and therefore how many versions to reserve)
+		// only exists once the group inserts/updates above have run, so this
+		// reservation happens here rather than in PreApply -- mirroring the
+		// original inline migration's ordering exactly.
+		if st.rdb == nil {
+			return fmt.Errorf("friend_group_defaults migration requires a Redis client for version reservation")
+		}
+		end, err := st.rdb.IncrBy(ctx, friendSeqKey, int64(st.friendUpdates)).Result()
+		if err != nil {
+			return fmt.Errorf("reserve friend versions: %w", err)
+		}
+		st.startFriend = end - int64(st.friendUpdates) + 1
+		st.friendReserved = &migrate.ReservedRange{Key: friendSeqKey, Start: st.startFriend, End: st.startFriend + int64(st.friendUpdates)}
+
+		if _, err := tx.ExecContext(ctx, `
+			UPDATE friend f
+			JOIN (
+				SELECT f.id,
+					   COALESCE(m.target_group_id, d.default_group_id) AS new_gid,
+					   ROW_NUMBER() OVER (ORDER BY f.id) AS rn
+				FROM friend f
+				JOIN tmp_defaults d ON d.uid = f.uid
+				LEFT JOIN tmp_member m ON m.uid = f.uid AND m.friend_uid = f.to_uid
+				WHERE COALESCE(f.is_deleted,0) = 0
+				  AND COALESCE(f.friend_group_id,0) <> COALESCE(m.target_group_id, d.default_group_id)
+			) t ON t.id = f.id
+			SET f.friend_group_id = t.new_gid,
+				f.version = (? + t.rn - 1)
+		`, st.startFriend); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// reserve counts the rows Up will touch (the same queries Up runs, here
+// run early since PreApply executes before Up in the migration lifecycle)
+// and claims non-overlapping version ranges for them via Redis IncrBy,
+// before Up issues a single UPDATE.
+func (st *friendGroupDefaultsState) reserve(ctx context.Context, tx bun.Tx, rdb *redis.Client) (*migrate.ReservedRange, error) {
+	if rdb == nil {
+		return nil, fmt.Errorf("friend_group_defaults migration requires a Redis client for version reservation")
+	}
+	st.rdb = rdb
+
+	if _, err := tx.ExecContext(ctx, `DROP TEMPORARY TABLE IF EXISTS tmp_fg_existing`); err != nil {
+		return nil, err
+	}
+	if _, err := tx.ExecContext(ctx, `
+		CREATE TEMPORARY TABLE tmp_fg_existing (PRIMARY KEY(id))
+		AS
+		SELECT fg.id
+		FROM friend_group fg
+		WHERE fg.name = ?
+	`, defaultFriendGroupName); err != nil {
+		return nil, err
+	}
+	if err := tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM tmp_fg_existing`).Scan(&st.existingGroups); err != nil {
+		return nil, err
+	}
+	if err := tx.QueryRowContext(ctx, `
+		SELECT COUNT(*)
+		FROM `+"`user`"+` u
+		LEFT JOIN friend_group fg ON fg.uid = u.uid AND fg.name = ?
+		WHERE fg.id IS NULL
+	`, defaultFriendGroupName).Scan(&st.missingGroups); err != nil {
+		return nil, err
+	}
+
+	groupCount := int64(st.missingGroups + st.existingGroups)
+	if groupCount > 0 {
+		end, err := rdb.IncrBy(ctx, friendGroupSeqKey, groupCount).Result()
+		if err != nil {
+			return nil, fmt.Errorf("reserve friend_group versions: %w", err)
+		}
+		start := end - groupCount + 1
+		st.startMissingGroup = start
+		st.startExistingGroup = start + int64(st.missingGroups)
+	}
+
+	// The friend.friend_group_id reservation happens later, inside Up: the
+	// row count it needs to know isn't settled until the group inserts
+	// above have actually run.
+	return &migrate.ReservedRange{Key: friendGroupSeqKey, Start: st.startMissingGroup, End: st.startMissingGroup + groupCount}, nil
+}
+
+// down is a best-effort revert: it clears the is_default flag this
+// migration set and leaves friend_group_id assignments alone, since the
+// original pre-migration assignment of friend.friend_group_id is not
+// recoverable once overwritten.
+func (st *friendGroupDefaultsState) down(ctx context.Context, tx bun.Tx) error {
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE friend_group
+		SET is_default = 0
+		WHERE name = ?
+	`, defaultFriendGroupName); err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package dbx
+
+import (
+	"math/rand"
+	"sync/atomic"
+	"time"
+)
+
+// BackoffStrategy computes the delay before the next retry attempt.
+// Implementations may inspect err (via retryReason) to wait less for a
+// cheap-to-resolve deadlock than for a lock-wait timeout that indicates
+// real contention.
+type BackoffStrategy interface {
+	// NextDelay returns how long to wait before retrying. attempt is
+	// 1-indexed: it is the attempt number that just failed with err.
+	NextDelay(attempt int, err error) time.Duration
+}
+
+// ExponentialJitterBackoff is a full-jitter exponential backoff:
+// random(0, min(Cap, Base*2^attempt)). Deadlocks use Base/4 since the DB
+// has already resolved the contention by aborting one side; lock_timeout
+// errors use 2*Cap since the row was already contended long enough to
+// time out.
+type ExponentialJitterBackoff struct {
+	Base time.Duration
+	Cap  time.Duration
+}
+
+func (b ExponentialJitterBackoff) NextDelay(attempt int, err error) time.Duration {
+	base, cap := b.Base, b.Cap
+	if base <= 0 {
+		base = 20 * time.Millisecond
+	}
+	if cap <= 0 {
+		cap = 2 * time.Second
+	}
+	switch retryReason(err) {
+	case "deadlock":
+		base /= 4
+		if base <= 0 {
+			base = time.Millisecond
+		}
+	case "lock_timeout":
+		cap *= 2
+	}
+	d := base << attempt
+	if d <= 0 || d > cap {
+		d = cap
+	}
+	return time.Duration(rand.Int63n(int64(d) + 1))
+}
+
+// DecorrelatedJitterBackoff is the AWS-style decorrelated-jitter backoff:
+// min(Cap, random(Base, prev*3)). prev is shared across all callers of a
+// single strategy instance, which is intentional -- the whole point of
+// decorrelated jitter is to keep concurrent retriers from synchronizing,
+// and a shared prev spreads them further than per-call state would.
+type DecorrelatedJitterBackoff struct {
+	Base time.Duration
+	Cap  time.Duration
+
+	prev int64 // atomic, nanoseconds of the last delay returned
+}
+
+func (b *DecorrelatedJitterBackoff) NextDelay(attempt int, err error) time.Duration {
+	base, cap := b.Base, b.Cap
+	if base <= 0 {
+		base = 20 * time.Millisecond
+	}
+	if cap <= 0 {
+		cap = 2 * time.Second
+	}
+	if retryReason(err) == "deadlock" {
+		base /= 4
+		if base <= 0 {
+			base = time.Millisecond
+		}
+	}
+
+	prev := time.Duration(atomic.LoadInt64(&b.prev))
+	if prev <= 0 {
+		prev = base
+	}
+	upper := prev * 3
+	if upper <= 0 || upper > cap {
+		upper = cap
+	}
+	if upper < base {
+		upper = base
+	}
+
+	d := base + time.Duration(rand.Int63n(int64(upper-base)+1))
+	atomic.StoreInt64(&b.prev, int64(d))
+	return d
+}
+
+// ConstantBackoff always waits Delay.
+type ConstantBackoff struct {
+	Delay time.Duration
+}
+
+func (b ConstantBackoff) NextDelay(attempt int, err error) time.Duration {
+	return b.Delay
+}
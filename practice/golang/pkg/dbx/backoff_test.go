@@ -0,0 +1,100 @@
+package dbx
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestExponentialJitterBackoff_WithinCap(t *testing.T) {
+	b := ExponentialJitterBackoff{Base: 10 * time.Millisecond, Cap: 200 * time.Millisecond}
+	for attempt := 1; attempt <= 10; attempt++ {
+		for i := 0; i < 20; i++ {
+			d := b.NextDelay(attempt, nil)
+			if d < 0 || d > b.Cap {
+				t.Fatalf("NextDelay(%d, nil) = %v, want within [0, %v]", attempt, d, b.Cap)
+			}
+		}
+	}
+}
+
+func TestExponentialJitterBackoff_DeadlockShrinksBase(t *testing.T) {
+	b := ExponentialJitterBackoff{Base: 100 * time.Millisecond, Cap: time.Second}
+	err := errors.New("deadlock detected")
+	for i := 0; i < 20; i++ {
+		d := b.NextDelay(1, err)
+		if d > b.Base {
+			t.Fatalf("NextDelay(1, deadlock) = %v, want <= Base/4-scaled bound %v", d, b.Base)
+		}
+	}
+}
+
+func TestExponentialJitterBackoff_LockTimeoutDoublesCap(t *testing.T) {
+	b := ExponentialJitterBackoff{Base: 10 * time.Millisecond, Cap: 100 * time.Millisecond}
+	err := errors.New("lock wait timeout exceeded")
+	saw := false
+	for i := 0; i < 200; i++ {
+		d := b.NextDelay(10, err)
+		if d > b.Cap {
+			saw = true
+		}
+		if d > 2*b.Cap {
+			t.Fatalf("NextDelay(10, lock_timeout) = %v, want <= 2*Cap %v", d, 2*b.Cap)
+		}
+	}
+	if !saw {
+		t.Error("expected at least one delay to exceed Cap when lock_timeout doubles it, got none in 200 tries")
+	}
+}
+
+func TestExponentialJitterBackoff_ZeroValueUsesDefaults(t *testing.T) {
+	var b ExponentialJitterBackoff
+	d := b.NextDelay(1, nil)
+	if d < 0 || d > 2*time.Second {
+		t.Fatalf("NextDelay(1, nil) on zero-value backoff = %v, want within default cap 2s", d)
+	}
+}
+
+func TestDecorrelatedJitterBackoff_WithinCap(t *testing.T) {
+	b := &DecorrelatedJitterBackoff{Base: 10 * time.Millisecond, Cap: 200 * time.Millisecond}
+	for attempt := 1; attempt <= 10; attempt++ {
+		d := b.NextDelay(attempt, nil)
+		if d < b.Base || d > b.Cap {
+			t.Fatalf("NextDelay(%d, nil) = %v, want within [Base, Cap] = [%v, %v]", attempt, d, b.Base, b.Cap)
+		}
+	}
+}
+
+func TestDecorrelatedJitterBackoff_GrowsFromPrev(t *testing.T) {
+	b := &DecorrelatedJitterBackoff{Base: time.Millisecond, Cap: time.Hour}
+	var last time.Duration
+	grew := false
+	for i := 0; i < 50; i++ {
+		d := b.NextDelay(1, nil)
+		if d > last {
+			grew = true
+		}
+		last = d
+	}
+	if !grew {
+		t.Error("expected decorrelated jitter to grow across at least one call given a huge cap, saw none")
+	}
+}
+
+func TestDecorrelatedJitterBackoff_DeadlockShrinksBase(t *testing.T) {
+	b := &DecorrelatedJitterBackoff{Base: 100 * time.Millisecond, Cap: time.Second}
+	err := errors.New("deadlock detected")
+	d := b.NextDelay(1, err)
+	if d < 0 || d > time.Second {
+		t.Fatalf("NextDelay(1, deadlock) = %v, want within [0, Cap]", d)
+	}
+}
+
+func TestConstantBackoff_AlwaysReturnsDelay(t *testing.T) {
+	b := ConstantBackoff{Delay: 50 * time.Millisecond}
+	for attempt := 1; attempt <= 3; attempt++ {
+		if got := b.NextDelay(attempt, nil); got != b.Delay {
+			t.Errorf("NextDelay(%d, nil) = %v, want %v", attempt, got, b.Delay)
+		}
+	}
+}
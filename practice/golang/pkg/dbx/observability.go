@@ -0,0 +1,122 @@
+package dbx
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/stoneMan1982/workexperience/practice/golang/pkg/retry"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// classifier is the shared SQLSTATE/MySQL-error classifier dbx retries
+// against -- the same one db.RunInTxWithRetry uses, so a deadlock/
+// serialization failure is classified identically everywhere in this
+// module instead of each retry path growing its own drifting copy of the
+// same switch statement. RetryLockTimeout is true because dbx's retry
+// paths have always retried MySQL 1205 unconditionally.
+var classifier = retry.NewClassifier(true)
+
+// reasonByCode maps retry.Code to the reason strings dbx's metrics and
+// BackoffStrategy implementations already key on.
+var reasonByCode = map[retry.Code]string{
+	retry.CodePGSerialization:  "serialization",
+	retry.CodePGDeadlock:       "deadlock",
+	retry.CodeMySQLDeadlock:    "deadlock",
+	retry.CodeMySQLLockTimeout: "lock_timeout",
+}
+
+const instrumentationName = "github.com/stoneMan1982/workexperience/practice/golang/pkg/dbx"
+
+// TxManagerOption configures the observability hooks on a TxManager.
+type TxManagerOption func(*TxManager)
+
+// WithTracer overrides the tracer TxManager.Run uses for its db.tx.* spans.
+// Defaults to otel.Tracer(instrumentationName), a no-op until the caller
+// registers a real SDK TracerProvider.
+func WithTracer(tracer trace.Tracer) TxManagerOption {
+	return func(m *TxManager) { m.tracer = tracer }
+}
+
+// WithMeter overrides the meter used for dbx_tx_* metrics. Defaults to
+// otel.Meter(instrumentationName).
+func WithMeter(meter metric.Meter) TxManagerOption {
+	return func(m *TxManager) { m.setMeter(meter) }
+}
+
+// setMeter builds the instruments TxManager.Run records to from meter.
+func (m *TxManager) setMeter(meter metric.Meter) {
+	m.meter = meter
+
+	var err error
+	m.txDuration, err = meter.Float64Histogram("dbx_tx_duration_seconds",
+		metric.WithDescription("Duration of TxManager.Run transactions"), metric.WithUnit("s"))
+	if err != nil {
+		slog.Warn("dbx: failed to create tx duration histogram", "err", err)
+	}
+	m.retryCounter, err = meter.Int64Counter("dbx_tx_retries_total",
+		metric.WithDescription("Number of TxManager.Run retries, by reason"))
+	if err != nil {
+		slog.Warn("dbx: failed to create tx retry counter", "err", err)
+	}
+}
+
+// startSpan starts a db.tx.* span tagged with the transaction's isolation
+// level and attempt number.
+func (m *TxManager) startSpan(ctx context.Context, name string, opts Options, attempt int) (context.Context, trace.Span) {
+	return m.tracer.Start(ctx, name, trace.WithAttributes(
+		attribute.Int("db.tx.isolation_level", int(opts.Isolation)),
+		attribute.Int("db.tx.attempt", attempt),
+	))
+}
+
+// recordRetry increments dbx_tx_retries_total for the given reason
+// ("serialization", "deadlock", or "lock_timeout" -- see retryReason).
+func (m *TxManager) recordRetry(ctx context.Context, reason string) {
+	if m.retryCounter == nil {
+		return
+	}
+	m.retryCounter.Add(ctx, 1, metric.WithAttributes(attribute.String("reason", reason)))
+}
+
+// recordDuration records dbx_tx_duration_seconds for one Run attempt.
+func (m *TxManager) recordDuration(ctx context.Context, d time.Duration, outcome string) {
+	if m.txDuration == nil {
+		return
+	}
+	m.txDuration.Record(ctx, d.Seconds(), metric.WithAttributes(attribute.String("outcome", outcome)))
+}
+
+// retryReason classifies err into the retry reason TxManager.Run should
+// report, or "" if err isn't retryable. isRetryableTxError previously did
+// this check without keeping the classification around, which made retry
+// storms impossible to attribute to a cause in metrics.
+//
+// Typed pgconn/mysql errors are classified via the shared pkg/retry
+// classifier; the string-matching fallback below only covers errors that
+// don't come back as one of those concrete types (e.g. a driver that
+// wraps its own error type, or sqlite's "database is locked").
+func retryReason(err error) string {
+	if err == nil {
+		return ""
+	}
+	if code, retryable := classifier.Classify(err); retryable {
+		return reasonByCode[code]
+	}
+	msg := err.Error()
+	switch {
+	case containsFold(msg, "deadlock"):
+		return "deadlock"
+	case containsFold(msg, "serialization"):
+		return "serialization"
+	case containsFold(msg, "timeout"):
+		return "lock_timeout"
+	}
+	return ""
+}
+
+func isRetryableTxError(err error) bool {
+	return retryReason(err) != ""
+}
@@ -0,0 +1,36 @@
+package dbx
+
+import (
+	"context"
+	"time"
+
+	"github.com/uptrace/bun"
+)
+
+// Outbox is a transactional-outbox row. EnqueueOutbox inserts one inside
+// the caller's own transaction, so the DB write it accompanies and the
+// "this needs publishing" record commit or roll back together. A separate
+// relay (rs.OutboxRelay) later claims unsent rows with
+// SelectForUpdateSkipLocked and forwards them to the message broker,
+// closing the gap where a DB commit and an XADD have no shared
+// transaction.
+type Outbox struct {
+	bun.BaseModel `bun:"table:outbox,alias:ob"`
+
+	ID        int64      `bun:"id,pk,autoincrement"`
+	Topic     string     `bun:"topic,notnull"`
+	Key       string     `bun:"key"` // shard/routing key; empty lets the relay pick a default
+	Payload   []byte     `bun:"payload,notnull"`
+	CreatedAt time.Time  `bun:"created_at,notnull,default:current_timestamp"`
+	SentAt    *time.Time `bun:"sent_at"`
+	Attempts  int        `bun:"attempts,notnull,default:0"`
+	LastError string     `bun:"last_error"`
+}
+
+// EnqueueOutbox inserts a row for topic/key/payload using tx, so it commits
+// atomically with the rest of the caller's transaction.
+func EnqueueOutbox(ctx context.Context, tx bun.Tx, topic, key string, payload []byte) error {
+	row := &Outbox{Topic: topic, Key: key, Payload: payload}
+	_, err := tx.NewInsert().Model(row).Exec(ctx)
+	return err
+}
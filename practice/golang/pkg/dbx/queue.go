@@ -2,22 +2,100 @@ package dbx
 
 import (
 	"context"
+	"log/slog"
+	"strings"
 
 	"github.com/uptrace/bun"
 )
 
-// SelectForUpdateSkipLocked is a helper to select rows for processing with SKIP LOCKED pattern.
-// It builds a query like:
+// LockKind selects the row-locking behavior LockingSelect requests.
+type LockKind string
+
+const (
+	// LockUpdate is a plain FOR UPDATE: block until the lock is free.
+	LockUpdate LockKind = "update"
+	// LockShare is FOR SHARE/LOCK IN SHARE MODE: block writers, allow other readers.
+	LockShare LockKind = "share"
+	// LockSkipLocked adds SKIP LOCKED: skip rows already locked by another tx,
+	// instead of blocking on them. The usual choice for "claim a batch of work".
+	LockSkipLocked LockKind = "skip_locked"
+	// LockNoWait adds NOWAIT: fail immediately instead of blocking if a row is locked.
+	LockNoWait LockKind = "nowait"
+)
+
+// LockMode configures the FOR UPDATE/SHARE trailer LockingSelect emits.
+type LockMode struct {
+	Kind LockKind
+	// Of names the tables to lock when the query joins lookup tables the
+	// caller does NOT want locked (Postgres only: FOR UPDATE OF <table>).
+	Of []string
+}
+
+// clause renders mode's FOR-clause trailer for dialect (bun's
+// tx.Dialect().Name().String(), e.g. "pg", "mysql", "sqlite", "mssql").
+// warn is non-empty when the dialect can't honor the request as asked and
+// LockingSelect degrades to the closest available behavior.
+func (m LockMode) clause(dialect string) (clause, warn string) {
+	base := "UPDATE"
+	if m.Kind == LockShare {
+		base = "SHARE"
+	}
+
+	switch dialect {
+	case "pg":
+		c := base
+		if len(m.Of) > 0 {
+			c += " OF " + strings.Join(m.Of, ", ")
+		}
+		switch m.Kind {
+		case LockSkipLocked:
+			c += " SKIP LOCKED"
+		case LockNoWait:
+			c += " NOWAIT"
+		}
+		return c, ""
+	case "mysql":
+		// MySQL 8 / MariaDB 10.6+ understand SKIP LOCKED and NOWAIT after
+		// FOR UPDATE/LOCK IN SHARE MODE, but have no OF clause.
+		c := base
+		switch m.Kind {
+		case LockSkipLocked:
+			c += " SKIP LOCKED"
+		case LockNoWait:
+			c += " NOWAIT"
+		}
+		if len(m.Of) > 0 {
+			return c, "dbx: LockingSelect ignores LockMode.Of on mysql (no FOR UPDATE OF support)"
+		}
+		return c, ""
+	case "sqlite":
+		// SQLite has no FOR UPDATE/FOR SHARE syntax at all -- unlike on
+		// pg/mysql, emitting it here isn't a degraded fallback, it's a parse
+		// error. Its own file/WAL-level locking already serializes writers
+		// for the duration of the transaction, so drop the trailer entirely
+		// and just let the caller know this is a no-op on this dialect.
+		return "", "dbx: LockingSelect has no FOR UPDATE/FOR SHARE on sqlite; relying on sqlite's own transaction-level locking instead"
+	default:
+		return base, ""
+	}
+}
+
+// LockingSelect is a dialect-aware "claim rows for processing" helper: it
+// builds
 //
 //	SELECT <columns>
 //	FROM <tableExpr>
 //	WHERE <whereExpr>
 //	ORDER BY <orderExpr>
 //	LIMIT <limit>
-//	FOR UPDATE SKIP LOCKED
+//	FOR <mode's dialect-specific trailer>
 //
-// Note: This is Postgres-specific due to SKIP LOCKED.
-func SelectForUpdateSkipLocked(ctx context.Context, tx bun.Tx, dest any, tableExpr, columns, whereExpr, orderExpr string, limit int, args ...any) error {
+// and inspects tx.Dialect().Name() to pick the right trailer: SKIP LOCKED on
+// Postgres/MySQL 8+, NOWAIT where supported, FOR UPDATE OF <table> on
+// Postgres when mode.Of is set, and no trailer at all (just a warning) on
+// SQLite, which has no FOR UPDATE/FOR SHARE syntax and relies on its own
+// transaction-level locking instead.
+func LockingSelect(ctx context.Context, tx bun.Tx, dest any, tableExpr, columns, whereExpr, orderExpr string, limit int, mode LockMode, args ...any) error {
 	q := tx.NewSelect().Model(dest).
 		TableExpr(tableExpr)
 	if columns != "" {
@@ -32,6 +110,23 @@ func SelectForUpdateSkipLocked(ctx context.Context, tx bun.Tx, dest any, tableEx
 	if limit > 0 {
 		q = q.Limit(limit)
 	}
-	q = q.For("UPDATE SKIP LOCKED")
+
+	dialect := strings.ToLower(tx.Dialect().Name().String())
+	clause, warn := mode.clause(dialect)
+	if warn != "" {
+		slog.Warn(warn, "dialect", dialect)
+	}
+	if clause != "" {
+		q = q.For(clause)
+	}
 	return q.Scan(ctx)
 }
+
+// SelectForUpdateSkipLocked selects rows with a "claim a batch of work"
+// SKIP LOCKED pattern, dialect-aware via LockingSelect. Kept as a thin
+// wrapper around LockingSelect(..., LockMode{Kind: LockSkipLocked}) since
+// it's the overwhelmingly common case and existing callers already use
+// this exact signature.
+func SelectForUpdateSkipLocked(ctx context.Context, tx bun.Tx, dest any, tableExpr, columns, whereExpr, orderExpr string, limit int, args ...any) error {
+	return LockingSelect(ctx, tx, dest, tableExpr, columns, whereExpr, orderExpr, limit, LockMode{Kind: LockSkipLocked}, args...)
+}
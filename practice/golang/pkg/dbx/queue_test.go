@@ -0,0 +1,45 @@
+package dbx
+
+import "testing"
+
+func TestLockMode_Clause(t *testing.T) {
+	cases := []struct {
+		name       string
+		mode       LockMode
+		dialect    string
+		wantClause string
+		wantWarn   bool
+	}{
+		{"pg update", LockMode{Kind: LockUpdate}, "pg", "UPDATE", false},
+		{"pg share", LockMode{Kind: LockShare}, "pg", "SHARE", false},
+		{"pg skip locked", LockMode{Kind: LockSkipLocked}, "pg", "UPDATE SKIP LOCKED", false},
+		{"pg nowait", LockMode{Kind: LockNoWait}, "pg", "UPDATE NOWAIT", false},
+		{"pg update of", LockMode{Kind: LockUpdate, Of: []string{"accounts"}}, "pg", "UPDATE OF accounts", false},
+		{"pg update of multiple", LockMode{Kind: LockUpdate, Of: []string{"a", "b"}}, "pg", "UPDATE OF a, b", false},
+		{"pg skip locked of", LockMode{Kind: LockSkipLocked, Of: []string{"accounts"}}, "pg", "UPDATE OF accounts SKIP LOCKED", false},
+
+		{"mysql update", LockMode{Kind: LockUpdate}, "mysql", "UPDATE", false},
+		{"mysql share", LockMode{Kind: LockShare}, "mysql", "SHARE", false},
+		{"mysql skip locked", LockMode{Kind: LockSkipLocked}, "mysql", "UPDATE SKIP LOCKED", false},
+		{"mysql nowait", LockMode{Kind: LockNoWait}, "mysql", "UPDATE NOWAIT", false},
+		{"mysql of warns and is dropped", LockMode{Kind: LockUpdate, Of: []string{"accounts"}}, "mysql", "UPDATE", true},
+
+		{"sqlite has no clause at all", LockMode{Kind: LockUpdate}, "sqlite", "", true},
+		{"sqlite skip locked still no clause", LockMode{Kind: LockSkipLocked}, "sqlite", "", true},
+
+		{"unknown dialect falls back to base", LockMode{Kind: LockUpdate}, "mssql", "UPDATE", false},
+		{"unknown dialect share falls back to base", LockMode{Kind: LockShare}, "mssql", "SHARE", false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			clause, warn := c.mode.clause(c.dialect)
+			if clause != c.wantClause {
+				t.Errorf("clause(%q) = %q, want %q", c.dialect, clause, c.wantClause)
+			}
+			if (warn != "") != c.wantWarn {
+				t.Errorf("clause(%q) warn = %q, want non-empty: %v", c.dialect, warn, c.wantWarn)
+			}
+		})
+	}
+}
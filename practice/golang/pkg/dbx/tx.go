@@ -7,9 +7,10 @@ import (
 	"fmt"
 	"time"
 
-	mysql "github.com/go-sql-driver/mysql"
-	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/uptrace/bun"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // TxOption configures transaction behavior.
@@ -40,6 +41,9 @@ func WithTx(ctx context.Context, db *bun.DB, opt *TxOption, fn func(ctx context.
 type RetryOption struct {
 	MaxAttempts    int
 	InitialBackoff time.Duration
+	// Backoff overrides the retry delay schedule. Defaults to
+	// ExponentialJitterBackoff{Base: InitialBackoff, Cap: 2s}.
+	Backoff BackoffStrategy
 }
 
 func (o *RetryOption) normalize() {
@@ -49,10 +53,15 @@ func (o *RetryOption) normalize() {
 	if o.InitialBackoff <= 0 {
 		o.InitialBackoff = 50 * time.Millisecond
 	}
+	if o.Backoff == nil {
+		o.Backoff = ExponentialJitterBackoff{Base: o.InitialBackoff, Cap: 2 * time.Second}
+	}
 }
 
-// WithSerializableRetry runs fn inside a serializable transaction and retries on
-// PostgreSQL serialization failures (SQLSTATE 40001). Useful with PG/Cockroach.
+// WithSerializableRetry runs fn inside a serializable transaction and
+// retries on transient errors (PG/MySQL deadlocks, PG serialization
+// failures, MySQL lock-wait timeouts) classified via the same retryReason
+// TxManager.Run uses. Useful with PG/Cockroach.
 func WithSerializableRetry(ctx context.Context, db *bun.DB, fn func(ctx context.Context, tx bun.Tx) error, ropt *RetryOption) error {
 	opt := &RetryOption{}
 	if ropt != nil {
@@ -60,23 +69,18 @@ func WithSerializableRetry(ctx context.Context, db *bun.DB, fn func(ctx context.
 	}
 	opt.normalize()
 
-	backoff := opt.InitialBackoff
 	for attempt := 0; attempt < opt.MaxAttempts; attempt++ {
 		err := WithTx(ctx, db, &TxOption{Isolation: sql.LevelSerializable}, fn)
 		if err == nil {
 			return nil
 		}
-		// Detect PG serialization error: SQLSTATE 40001
-		var pgErr *pgconn.PgError
-		if errors.As(err, &pgErr) && pgErr.SQLState() == "40001" {
-			// retry with backoff
-			timer := time.NewTimer(backoff)
+		if retryReason(err) != "" {
+			timer := time.NewTimer(opt.Backoff.NextDelay(attempt+1, err))
 			select {
 			case <-ctx.Done():
 				return ctx.Err()
 			case <-timer.C:
 			}
-			backoff *= 2
 			continue
 		}
 		return err
@@ -111,10 +115,32 @@ type TxManager struct {
 	DB             *bun.DB
 	MaxAttempts    int
 	InitialBackoff time.Duration
+	// MaxElapsed, if positive, bounds the total time Run spends retrying
+	// across all attempts -- whichever of MaxAttempts or MaxElapsed is hit
+	// first stops the retry loop.
+	MaxElapsed time.Duration
+	// Backoff computes the delay between retry attempts. Defaults to
+	// ExponentialJitterBackoff{Base: InitialBackoff, Cap: 2s}.
+	Backoff BackoffStrategy
+
+	tracer       trace.Tracer
+	meter        metric.Meter
+	txDuration   metric.Float64Histogram
+	retryCounter metric.Int64Counter
 }
 
-func NewTxManager(db *bun.DB) *TxManager {
-	return &TxManager{DB: db, MaxAttempts: 1, InitialBackoff: 50 * time.Millisecond}
+// NewTxManager builds a TxManager. By default it observes via
+// otel.Tracer/otel.Meter(instrumentationName), which are no-ops until the
+// caller registers real SDK providers; pass WithTracer/WithMeter to plug in
+// specific ones instead.
+func NewTxManager(db *bun.DB, opts ...TxManagerOption) *TxManager {
+	m := &TxManager{DB: db, MaxAttempts: 1, InitialBackoff: 50 * time.Millisecond}
+	m.tracer = otel.Tracer(instrumentationName)
+	m.setMeter(otel.Meter(instrumentationName))
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
 }
 
 // InTx returns current bun.Tx if present in context.
@@ -159,30 +185,40 @@ func (m *TxManager) Run(ctx context.Context, opts Options, fn func(context.Conte
 	if m.InitialBackoff <= 0 {
 		m.InitialBackoff = 50 * time.Millisecond
 	}
+	if m.Backoff == nil {
+		m.Backoff = ExponentialJitterBackoff{Base: m.InitialBackoff, Cap: 2 * time.Second}
+	}
 
+	start := time.Now()
 	attempt := 0
-	backoff := m.InitialBackoff
 	for {
-		err := m.runOnce(ctx, opts, fn)
+		attempt++
+		err := m.runOnce(ctx, opts, attempt, fn)
 		if err == nil {
 			return nil
 		}
-		attempt++
-		if attempt >= m.MaxAttempts || !isRetryableTxError(err) || ctx.Err() != nil {
+		reason := retryReason(err)
+		if reason == "" || ctx.Err() != nil {
+			return err
+		}
+		if attempt >= m.MaxAttempts {
 			return err
 		}
-		// backoff
-		t := time.NewTimer(backoff)
+		if m.MaxElapsed > 0 && time.Since(start) >= m.MaxElapsed {
+			return err
+		}
+		m.recordRetry(ctx, reason)
+
+		t := time.NewTimer(m.Backoff.NextDelay(attempt, err))
 		select {
 		case <-ctx.Done():
 			return ctx.Err()
 		case <-t.C:
 		}
-		backoff *= 2
 	}
 }
 
-func (m *TxManager) runOnce(ctx context.Context, opts Options, fn func(context.Context, bun.Tx) error) error {
+func (m *TxManager) runOnce(ctx context.Context, opts Options, attempt int, fn func(context.Context, bun.Tx) error) error {
 	if outerTx, ok := InTx(ctx); ok && !opts.RequiresNew {
 		// reuse current tx scope (no savepoint). Just run.
 		tctx := ctx.Value(txCtxKey{}).(*txContext)
@@ -194,11 +230,14 @@ func (m *TxManager) runOnce(ctx context.Context, opts Options, fn func(context.C
 	if outerTx, ok := InTx(ctx); ok && opts.RequiresNew {
 		// nested with savepoint
 		name := savepointName(opts.SavepointNameHint)
-		if err := execSavepoint(ctx, outerTx, name); err != nil {
+		spanCtx, span := m.startSpan(ctx, "db.tx.savepoint", opts, attempt)
+		err := execSavepoint(spanCtx, outerTx, name)
+		span.End()
+		if err != nil {
 			return err
 		}
 		// run nested
-		err := fn(ctx, outerTx)
+		err = fn(ctx, outerTx)
 		if err != nil {
 			_ = rollbackToSavepoint(ctx, outerTx, name)
 			return err
@@ -207,8 +246,11 @@ func (m *TxManager) runOnce(ctx context.Context, opts Options, fn func(context.C
 	}
 
 	// top-level: begin tx
+	start := time.Now()
+	beginCtx, beginSpan := m.startSpan(ctx, "db.tx.begin", opts, attempt)
 	txOpt := &sql.TxOptions{Isolation: opts.Isolation, ReadOnly: opts.ReadOnly}
-	tx, err := m.DB.BeginTx(ctx, txOpt)
+	tx, err := m.DB.BeginTx(beginCtx, txOpt)
+	beginSpan.End()
 	if err != nil {
 		return err
 	}
@@ -225,12 +267,21 @@ func (m *TxManager) runOnce(ctx context.Context, opts Options, fn func(context.C
 
 	// run user fn
 	if err := fn(ctx, tx); err != nil {
+		_, rbSpan := m.startSpan(ctx, "db.tx.rollback", opts, attempt)
 		_ = tx.Rollback()
+		rbSpan.End()
+		m.recordDuration(ctx, time.Since(start), "rollback")
 		return err
 	}
-	if err := tx.Commit(); err != nil {
+	_, commitSpan := m.startSpan(ctx, "db.tx.commit", opts, attempt)
+	err = tx.Commit()
+	commitSpan.End()
+	if err != nil {
+		m.recordDuration(ctx, time.Since(start), "error")
 		return err
 	}
+	m.recordDuration(ctx, time.Since(start), "commit")
+
 	// run after-commit hooks
 	for _, f := range tctx.afterCommit {
 		safeCall(f)
@@ -263,45 +314,9 @@ func rollbackToSavepoint(ctx context.Context, tx bun.Tx, name string) error {
 	return err
 }
 
-// isRetryableTxError decides if an error is worth retrying.
-func isRetryableTxError(err error) bool {
-	if err == nil {
-		return false
-	}
-	// PostgreSQL: serialization_failure 40001, deadlock_detected 40P01
-	var pgErr *pgconn.PgError
-	if errors.As(err, &pgErr) {
-		code := pgErr.SQLState()
-		if code == "40001" || code == "40P01" {
-			return true
-		}
-	}
-	// MySQL: ER_LOCK_DEADLOCK 1213, ER_LOCK_WAIT_TIMEOUT 1205
-	var myErr *mysql.MySQLError
-	if errors.As(err, &myErr) {
-		if myErr.Number == 1213 || myErr.Number == 1205 {
-			return true
-		}
-	}
-	// Fallback: text contains hints (best-effort)
-	msg := err.Error()
-	if containsAnyFold(msg, "deadlock", "serialization", "timeout") {
-		return true
-	}
-	return false
-}
-
-func containsAnyFold(s string, subs ...string) bool {
-	for _, sub := range subs {
-		if sub == "" {
-			continue
-		}
-		if containsFold(s, sub) {
-			return true
-		}
-	}
-	return false
-}
+// isRetryableTxError and its reason classification now live in
+// observability.go as retryReason, so the retry metric can report *why* a
+// transaction was retried instead of just that it was.
 
 func containsFold(s, sub string) bool {
 	// cheap case-insensitive contains
@@ -0,0 +1,74 @@
+// Package health provides a pluggable readiness-probe registry used by the
+// healthcheck CLI subcommand (and anything else that wants to answer "is
+// this process healthy") without hardwiring knowledge of every subsystem.
+package health
+
+import (
+	"context"
+	"time"
+)
+
+// Checker is a single readiness probe. Packages that own a resource (a DB
+// pool, a migration cursor table, a queue connection) implement this to
+// register themselves with a Registry.
+type Checker interface {
+	Name() string
+	Check(ctx context.Context) error
+}
+
+// CheckFunc adapts a plain function to the Checker interface.
+type CheckFunc func(ctx context.Context) error
+
+type funcChecker struct {
+	name string
+	fn   CheckFunc
+}
+
+func (f funcChecker) Name() string                    { return f.name }
+func (f funcChecker) Check(ctx context.Context) error { return f.fn(ctx) }
+
+// NewFunc builds a Checker from a name and a check function.
+func NewFunc(name string, fn CheckFunc) Checker {
+	return funcChecker{name: name, fn: fn}
+}
+
+// Result is the outcome of running one Checker.
+type Result struct {
+	Name    string        `json:"name"`
+	OK      bool          `json:"ok"`
+	Error   string        `json:"error,omitempty"`
+	Latency time.Duration `json:"latency_ns"`
+}
+
+// Registry holds the set of probes to run for a readiness check.
+type Registry struct {
+	checkers []Checker
+}
+
+// NewRegistry builds an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// Register adds a Checker to the registry.
+func (r *Registry) Register(c Checker) {
+	r.checkers = append(r.checkers, c)
+}
+
+// Run executes every registered Checker and returns whether all of them
+// passed, along with a per-checker Result.
+func (r *Registry) Run(ctx context.Context) (bool, []Result) {
+	results := make([]Result, 0, len(r.checkers))
+	allOK := true
+	for _, c := range r.checkers {
+		start := time.Now()
+		err := c.Check(ctx)
+		res := Result{Name: c.Name(), OK: err == nil, Latency: time.Since(start)}
+		if err != nil {
+			res.Error = err.Error()
+			allOK = false
+		}
+		results = append(results, res)
+	}
+	return allOK, results
+}
@@ -0,0 +1,22 @@
+package health
+
+import (
+	"context"
+
+	redis "github.com/redis/go-redis/v9"
+	"github.com/uptrace/bun"
+)
+
+// DBChecker pings a bun.DB.
+func DBChecker(name string, db *bun.DB) Checker {
+	return NewFunc(name, func(ctx context.Context) error {
+		return db.PingContext(ctx)
+	})
+}
+
+// RedisChecker pings a redis client.
+func RedisChecker(name string, rdb *redis.Client) Checker {
+	return NewFunc(name, func(ctx context.Context) error {
+		return rdb.Ping(ctx).Err()
+	})
+}
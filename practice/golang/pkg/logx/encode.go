@@ -0,0 +1,18 @@
+package logx
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+)
+
+// encodeJSONLine renders r as a single JSON line, reusing slog's own JSON
+// handler so field names/encoding stay consistent with StdoutSink's output.
+func encodeJSONLine(r slog.Record) ([]byte, error) {
+	var buf bytes.Buffer
+	h := slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})
+	if err := h.Handle(context.Background(), r); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
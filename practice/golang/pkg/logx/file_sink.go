@@ -0,0 +1,121 @@
+package logx
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// FileSink writes JSON-encoded records to a file, rotating it when it
+// exceeds MaxSizeBytes or is older than MaxAge (lumberjack-style: the
+// current file is renamed with a timestamp suffix and a fresh one opened).
+// Rotated files beyond MaxBackups are deleted, oldest first.
+type FileSink struct {
+	Path         string
+	MaxSizeBytes int64
+	MaxAge       time.Duration
+	MaxBackups   int
+
+	mu       sync.Mutex
+	f        *os.File
+	size     int64
+	openedAt time.Time
+}
+
+// NewFileSink opens (creating if needed) the log file at path.
+func NewFileSink(path string, maxSizeBytes int64, maxAge time.Duration, maxBackups int) (*FileSink, error) {
+	s := &FileSink{Path: path, MaxSizeBytes: maxSizeBytes, MaxAge: maxAge, MaxBackups: maxBackups}
+	if err := s.open(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *FileSink) open() error {
+	if err := os.MkdirAll(filepath.Dir(s.Path), 0o755); err != nil {
+		return fmt.Errorf("logx: mkdir for file sink: %w", err)
+	}
+	f, err := os.OpenFile(s.Path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("logx: open file sink: %w", err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("logx: stat file sink: %w", err)
+	}
+	s.f = f
+	s.size = info.Size()
+	s.openedAt = time.Now()
+	return nil
+}
+
+func (s *FileSink) Write(ctx context.Context, r slog.Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.shouldRotateLocked() {
+		if err := s.rotateLocked(); err != nil {
+			return err
+		}
+	}
+
+	line, err := encodeJSONLine(r)
+	if err != nil {
+		return err
+	}
+	n, err := s.f.Write(line)
+	s.size += int64(n)
+	return err
+}
+
+func (s *FileSink) shouldRotateLocked() bool {
+	if s.MaxSizeBytes > 0 && s.size >= s.MaxSizeBytes {
+		return true
+	}
+	if s.MaxAge > 0 && time.Since(s.openedAt) >= s.MaxAge {
+		return true
+	}
+	return false
+}
+
+func (s *FileSink) rotateLocked() error {
+	if err := s.f.Close(); err != nil {
+		return fmt.Errorf("logx: close file sink before rotate: %w", err)
+	}
+	rotated := fmt.Sprintf("%s.%s", s.Path, time.Now().UTC().Format("20060102T150405.000000000"))
+	if err := os.Rename(s.Path, rotated); err != nil {
+		return fmt.Errorf("logx: rotate file sink: %w", err)
+	}
+	if err := s.open(); err != nil {
+		return err
+	}
+	s.pruneBackupsLocked()
+	return nil
+}
+
+func (s *FileSink) pruneBackupsLocked() {
+	if s.MaxBackups <= 0 {
+		return
+	}
+	matches, err := filepath.Glob(s.Path + ".*")
+	if err != nil || len(matches) <= s.MaxBackups {
+		return
+	}
+	// Glob returns lexically sorted names; our suffix is a sortable
+	// timestamp, so the oldest backups are the earliest entries.
+	excess := len(matches) - s.MaxBackups
+	for _, old := range matches[:excess] {
+		_ = os.Remove(old)
+	}
+}
+
+func (s *FileSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.f.Close()
+}
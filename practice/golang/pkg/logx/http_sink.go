@@ -0,0 +1,158 @@
+package logx
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// HTTPSink batches JSON-encoded records and ships them to a remote
+// collector over HTTP. Records are queued in memory up to QueueSize; once
+// full, the oldest queued record is dropped to make room for the newest
+// one, so a slow or unreachable collector can never block callers of
+// Write.
+type HTTPSink struct {
+	URL           string
+	QueueSize     int
+	BatchSize     int
+	FlushInterval time.Duration
+	MaxRetries    int
+	Client        *http.Client
+
+	mu      sync.Mutex
+	queue   [][]byte
+	dropped uint64
+	closed  bool
+	closeCh chan struct{}
+	flushCh chan struct{}
+	doneCh  chan struct{}
+}
+
+// NewHTTPSink starts a background flush loop that posts batches of records
+// to url. queueSize bounds how many records are held in memory; batchSize
+// is the max number of records per POST; flushInterval is how often a
+// partial batch is flushed even if it hasn't reached batchSize.
+func NewHTTPSink(url string, queueSize, batchSize int, flushInterval time.Duration) *HTTPSink {
+	if queueSize <= 0 {
+		queueSize = 1000
+	}
+	if batchSize <= 0 {
+		batchSize = 100
+	}
+	if flushInterval <= 0 {
+		flushInterval = time.Second
+	}
+	s := &HTTPSink{
+		URL:           url,
+		QueueSize:     queueSize,
+		BatchSize:     batchSize,
+		FlushInterval: flushInterval,
+		MaxRetries:    3,
+		Client:        &http.Client{Timeout: 5 * time.Second},
+		closeCh:       make(chan struct{}),
+		doneCh:        make(chan struct{}),
+		flushCh:       make(chan struct{}, 1),
+	}
+	go s.loop()
+	return s
+}
+
+func (s *HTTPSink) Write(_ context.Context, r slog.Record) error {
+	line, err := encodeJSONLine(r)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return fmt.Errorf("logx: http sink is closed")
+	}
+	if len(s.queue) >= s.QueueSize {
+		s.queue = s.queue[1:]
+		s.dropped++
+	}
+	s.queue = append(s.queue, line)
+	full := len(s.queue) >= s.BatchSize
+	s.mu.Unlock()
+
+	if full {
+		select {
+		case s.flushCh <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+func (s *HTTPSink) loop() {
+	defer close(s.doneCh)
+	ticker := time.NewTicker(s.FlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.flush()
+		case <-s.flushCh:
+			s.flush()
+		case <-s.closeCh:
+			s.flush()
+			return
+		}
+	}
+}
+
+func (s *HTTPSink) flush() {
+	s.mu.Lock()
+	if len(s.queue) == 0 {
+		s.mu.Unlock()
+		return
+	}
+	batch := s.queue
+	s.queue = nil
+	s.mu.Unlock()
+
+	payload := bytes.Join(batch, []byte("\n"))
+	for attempt := 0; attempt <= s.MaxRetries; attempt++ {
+		if s.post(payload) {
+			return
+		}
+		if attempt < s.MaxRetries {
+			time.Sleep(time.Duration(attempt+1) * 200 * time.Millisecond)
+		}
+	}
+	slog.Warn("logx: dropping batch after exhausting http sink retries", "records", len(batch), "url", s.URL)
+}
+
+func (s *HTTPSink) post(payload []byte) bool {
+	req, err := http.NewRequest(http.MethodPost, s.URL, bytes.NewReader(payload))
+	if err != nil {
+		return false
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return false
+	}
+	defer resp.Body.Close()
+	return resp.StatusCode >= 200 && resp.StatusCode < 300
+}
+
+// Close flushes any queued records and stops the background flush loop.
+func (s *HTTPSink) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	s.mu.Unlock()
+
+	close(s.closeCh)
+	<-s.doneCh
+	return nil
+}
@@ -8,6 +8,7 @@ import (
 	"path/filepath"
 	"runtime"
 	"strings"
+	"sync"
 )
 
 // Options controls logger initialization.
@@ -57,6 +58,58 @@ func Setup(level, format string, addSource bool) *slog.Logger {
 	return Init(Options{Level: lvl, JSON: json, AddSource: addSource, SetDefault: true})
 }
 
+// activeSinks holds whatever sinks SetupSinks most recently opened, so
+// Fatal/FatalContext can flush and close them (e.g. an HTTPSink's pending
+// batch) before the process exits.
+var (
+	activeSinksMu sync.Mutex
+	activeSinks   interface{ Close() error }
+)
+
+// SetupSinks initializes the global logger to fan out to the given sinks
+// instead of a single stdout writer. If specs is empty, it falls back to
+// the plain Setup(level, format, addSource) behavior. The existing
+// conditionalSourceHandler composes on top of the fanout exactly as it
+// would on top of a single handler.
+func SetupSinks(level, format string, addSource bool, specs []SinkSpec) (*slog.Logger, error) {
+	if len(specs) == 0 {
+		return Setup(level, format, addSource), nil
+	}
+
+	routes, err := BuildRoutes(specs)
+	if err != nil {
+		return nil, err
+	}
+	mh := newMultiHandler(routes)
+
+	activeSinksMu.Lock()
+	activeSinks = mh
+	activeSinksMu.Unlock()
+
+	var h slog.Handler = mh
+	if addSource {
+		h = &conditionalSourceHandler{h: mh}
+	}
+	l := slog.New(h)
+	slog.SetDefault(l)
+	return l, nil
+}
+
+// closeActiveSinks closes whatever sinks SetupSinks opened, if any. It is
+// called before Fatal/FatalContext exit the process so buffered sinks
+// (e.g. HTTPSink's in-flight batch) aren't silently dropped.
+func closeActiveSinks() {
+	activeSinksMu.Lock()
+	sinks := activeSinks
+	activeSinksMu.Unlock()
+	if sinks == nil {
+		return
+	}
+	if err := sinks.Close(); err != nil {
+		fmt.Fprintf(os.Stderr, "logx: error closing sinks: %v\n", err)
+	}
+}
+
 func handler(opts Options) slog.Handler {
 	optsLevel := &slog.LevelVar{}
 	if opts.Level == 0 {
@@ -121,15 +174,19 @@ func envOr(key, def string) string {
 	return def
 }
 
-// Fatal logs with FATAL level and then calls os.Exit(1).
+// Fatal logs with FATAL level, flushes and closes any sinks opened via
+// SetupSinks, and then calls os.Exit(1).
 func Fatal(msg string, args ...any) {
 	slog.Log(context.Background(), LevelFatal, msg, args...)
+	closeActiveSinks()
 	os.Exit(1)
 }
 
-// FatalContext logs with FATAL level with a context and then calls os.Exit(1).
+// FatalContext logs with FATAL level with a context, flushes and closes any
+// sinks opened via SetupSinks, and then calls os.Exit(1).
 func FatalContext(ctx context.Context, msg string, args ...any) {
 	slog.Log(ctx, LevelFatal, msg, args...)
+	closeActiveSinks()
 	os.Exit(1)
 }
 
@@ -0,0 +1,146 @@
+package logx
+
+import (
+	"context"
+	"log/slog"
+)
+
+// Sink receives a copy of every slog.Record routed to it by a fanout
+// handler. Implementations must be safe for concurrent use, since Handle
+// may be called from multiple goroutines.
+type Sink interface {
+	Write(ctx context.Context, r slog.Record) error
+	Close() error
+}
+
+// AttrFilter decides whether a record should reach a sink, beyond the
+// level check. Return true to let the record through.
+type AttrFilter func(r slog.Record) bool
+
+// sinkRoute pairs a Sink with the level/attr filters that decide which
+// records it receives.
+type sinkRoute struct {
+	sink       Sink
+	minLevel   slog.Level
+	attrFilter AttrFilter
+}
+
+// Route describes one fanout destination: a Sink plus the minimum level
+// (LevelFilter) and optional extra predicate (AttrFilter) a record must
+// pass to be written to it.
+type Route struct {
+	Sink       Sink
+	MinLevel   slog.Level
+	AttrFilter AttrFilter
+}
+
+// groupOrAttrs is one entry in multiHandler's WithAttrs/WithGroup history,
+// in call order -- exactly the sequence a record's own attrs need folding
+// through to get slog's qualified-key semantics right (attrs added before a
+// WithGroup stay top-level; attrs added after it, and the record's own
+// attrs, nest under that group).
+type groupOrAttrs struct {
+	group string      // non-empty: this entry is a WithGroup(group)
+	attrs []slog.Attr // empty group: this entry is a WithAttrs(attrs)
+}
+
+// multiHandler is a slog.Handler that fans every record out to N sinks,
+// each gated by its own level and attribute filter.
+type multiHandler struct {
+	routes []sinkRoute
+	goas   []groupOrAttrs
+}
+
+// newMultiHandler builds a multiHandler from routes. At least one route is
+// required; StdoutSink with LevelFilter(slog.LevelDebug) reproduces the
+// package's original single-writer behavior.
+func newMultiHandler(routes []Route) *multiHandler {
+	h := &multiHandler{routes: make([]sinkRoute, len(routes))}
+	for i, r := range routes {
+		h.routes[i] = sinkRoute{sink: r.Sink, minLevel: r.MinLevel, attrFilter: r.AttrFilter}
+	}
+	return h
+}
+
+func (h *multiHandler) Enabled(_ context.Context, level slog.Level) bool {
+	for _, r := range h.routes {
+		if level >= r.minLevel {
+			return true
+		}
+	}
+	return false
+}
+
+func (h *multiHandler) Handle(ctx context.Context, r slog.Record) error {
+	rec := r
+	if len(h.goas) > 0 {
+		var leaf []slog.Attr
+		r.Attrs(func(a slog.Attr) bool {
+			leaf = append(leaf, a)
+			return true
+		})
+		for i := len(h.goas) - 1; i >= 0; i-- {
+			g := h.goas[i]
+			if g.group != "" {
+				leaf = []slog.Attr{slog.Group(g.group, attrsToAny(leaf)...)}
+			} else {
+				leaf = append(append([]slog.Attr{}, g.attrs...), leaf...)
+			}
+		}
+		rec = slog.NewRecord(r.Time, r.Level, r.Message, r.PC)
+		rec.AddAttrs(leaf...)
+	}
+
+	var firstErr error
+	for _, route := range h.routes {
+		if rec.Level < route.minLevel {
+			continue
+		}
+		if route.attrFilter != nil && !route.attrFilter(rec) {
+			continue
+		}
+		if err := route.sink.Write(ctx, rec); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// attrsToAny adapts a []slog.Attr to the ...any slog.Group expects.
+func attrsToAny(attrs []slog.Attr) []any {
+	out := make([]any, len(attrs))
+	for i, a := range attrs {
+		out[i] = a
+	}
+	return out
+}
+
+func (h *multiHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	next := *h
+	next.goas = append(append([]groupOrAttrs{}, h.goas...), groupOrAttrs{attrs: attrs})
+	return &next
+}
+
+func (h *multiHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	next := *h
+	next.goas = append(append([]groupOrAttrs{}, h.goas...), groupOrAttrs{group: name})
+	return &next
+}
+
+// Close closes every sink, returning the first error encountered (if any)
+// after attempting to close them all.
+func (h *multiHandler) Close() error {
+	var firstErr error
+	for _, r := range h.routes {
+		if err := r.sink.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
@@ -0,0 +1,98 @@
+package logx
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// SinkSpec describes one fanout destination, built from config so logx
+// itself has no dependency on the config package. Callers translate
+// config.SinkConfig into SinkSpec values (see cmd/*/main.go).
+type SinkSpec struct {
+	Type     string // "stdout" | "file" | "syslog" | "http"
+	MinLevel string // debug|info|warn|error|fatal; defaults to debug
+
+	Stdout *StdoutSinkSpec
+	File   *FileSinkSpec
+	Syslog *SyslogSinkSpec
+	HTTP   *HTTPSinkSpec
+}
+
+type StdoutSinkSpec struct {
+	JSON bool
+}
+
+type FileSinkSpec struct {
+	Path         string
+	MaxSizeBytes int64
+	MaxAge       time.Duration
+	MaxBackups   int
+}
+
+type SyslogSinkSpec struct {
+	Network string
+	Addr    string
+	Tag     string
+}
+
+type HTTPSinkSpec struct {
+	URL           string
+	QueueSize     int
+	BatchSize     int
+	FlushInterval time.Duration
+}
+
+// BuildRoutes constructs the Sink for each spec and returns the resulting
+// Route list. Sinks that were already opened (e.g. FileSink, SyslogSink)
+// are owned by the returned routes; callers should not build the same spec
+// twice.
+func BuildRoutes(specs []SinkSpec) ([]Route, error) {
+	routes := make([]Route, 0, len(specs))
+	for _, spec := range specs {
+		minLevel := slogLevelOrDebug(spec.MinLevel)
+		var sink Sink
+		switch spec.Type {
+		case "stdout":
+			json := true
+			if spec.Stdout != nil {
+				json = spec.Stdout.JSON
+			}
+			sink = NewStdoutSink(nil, json)
+		case "file":
+			if spec.File == nil {
+				return nil, fmt.Errorf("logx: sink type file requires a File spec")
+			}
+			fs, err := NewFileSink(spec.File.Path, spec.File.MaxSizeBytes, spec.File.MaxAge, spec.File.MaxBackups)
+			if err != nil {
+				return nil, err
+			}
+			sink = fs
+		case "syslog":
+			if spec.Syslog == nil {
+				return nil, fmt.Errorf("logx: sink type syslog requires a Syslog spec")
+			}
+			ss, err := NewSyslogSink(spec.Syslog.Network, spec.Syslog.Addr, spec.Syslog.Tag)
+			if err != nil {
+				return nil, err
+			}
+			sink = ss
+		case "http":
+			if spec.HTTP == nil {
+				return nil, fmt.Errorf("logx: sink type http requires an HTTP spec")
+			}
+			sink = NewHTTPSink(spec.HTTP.URL, spec.HTTP.QueueSize, spec.HTTP.BatchSize, spec.HTTP.FlushInterval)
+		default:
+			return nil, fmt.Errorf("logx: unknown sink type %q", spec.Type)
+		}
+		routes = append(routes, Route{Sink: sink, MinLevel: minLevel})
+	}
+	return routes, nil
+}
+
+func slogLevelOrDebug(s string) slog.Level {
+	if s == "" {
+		return slog.LevelDebug
+	}
+	return parseLevel(s)
+}
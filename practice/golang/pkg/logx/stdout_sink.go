@@ -0,0 +1,40 @@
+package logx
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"sync"
+)
+
+// StdoutSink writes records through a slog.Handler (JSON or text) to a
+// writer, by default os.Stdout -- this reproduces logx's original
+// single-writer behavior as one Sink among possibly several.
+type StdoutSink struct {
+	mu sync.Mutex
+	h  slog.Handler
+}
+
+// NewStdoutSink builds a StdoutSink. If w is nil, it writes to os.Stdout.
+func NewStdoutSink(w io.Writer, json bool) *StdoutSink {
+	if w == nil {
+		w = os.Stdout
+	}
+	opts := &slog.HandlerOptions{Level: slog.LevelDebug}
+	var h slog.Handler
+	if json {
+		h = slog.NewJSONHandler(w, opts)
+	} else {
+		h = slog.NewTextHandler(w, opts)
+	}
+	return &StdoutSink{h: h}
+}
+
+func (s *StdoutSink) Write(ctx context.Context, r slog.Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.h.Handle(ctx, r)
+}
+
+func (s *StdoutSink) Close() error { return nil }
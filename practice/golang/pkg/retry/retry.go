@@ -0,0 +1,126 @@
+// Package retry classifies database errors as retryable and computes
+// backoff delays for callers that want to retry transient failures
+// (deadlocks, lock-wait timeouts, serialization failures) instead of
+// propagating them to the user.
+package retry
+
+import (
+	"errors"
+	"math/rand"
+	"time"
+
+	mysql "github.com/go-sql-driver/mysql"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Code identifies the kind of retryable condition an error was classified
+// as, for logging and metrics.
+type Code string
+
+const (
+	CodeNone             Code = ""
+	CodeMySQLDeadlock    Code = "mysql_deadlock"    // error 1213
+	CodeMySQLLockTimeout Code = "mysql_lock_timeout" // error 1205
+	CodePGSerialization  Code = "pg_serialization"   // SQLSTATE 40001
+	CodePGDeadlock       Code = "pg_deadlock"        // SQLSTATE 40P01
+)
+
+// Classifier decides whether an error is worth retrying and, if so, under
+// what Code. Callers can register additional SQLSTATEs/error numbers by
+// wrapping or replacing the DefaultClassifier.
+type Classifier interface {
+	Classify(err error) (code Code, retryable bool)
+}
+
+// ClassifierFunc adapts a function to a Classifier.
+type ClassifierFunc func(err error) (Code, bool)
+
+func (f ClassifierFunc) Classify(err error) (Code, bool) { return f(err) }
+
+// sqlstateClassifier retries a fixed set of Postgres SQLSTATEs and MySQL
+// error numbers. RetryLockTimeout additionally treats MySQL 1205 (lock wait
+// timeout) as retryable, since that is only safe when the caller expects
+// long-running contention rather than a genuine deadlock.
+type sqlstateClassifier struct {
+	retryLockTimeout bool
+	extraSQLStates   map[string]Code
+	extraMySQLErrors map[uint16]Code
+}
+
+// NewClassifier returns the default Classifier used by db.RunInTxWithRetry.
+// retryLockTimeout controls whether MySQL 1205 (ER_LOCK_WAIT_TIMEOUT) is
+// treated as retryable.
+func NewClassifier(retryLockTimeout bool) Classifier {
+	return &sqlstateClassifier{retryLockTimeout: retryLockTimeout}
+}
+
+// RegisterSQLState makes c additionally retry the given Postgres SQLSTATE,
+// reporting it under code.
+func (c *sqlstateClassifier) RegisterSQLState(sqlstate string, code Code) {
+	if c.extraSQLStates == nil {
+		c.extraSQLStates = make(map[string]Code)
+	}
+	c.extraSQLStates[sqlstate] = code
+}
+
+// RegisterMySQLError makes c additionally retry the given MySQL error
+// number, reporting it under code.
+func (c *sqlstateClassifier) RegisterMySQLError(number uint16, code Code) {
+	if c.extraMySQLErrors == nil {
+		c.extraMySQLErrors = make(map[uint16]Code)
+	}
+	c.extraMySQLErrors[number] = code
+}
+
+func (c *sqlstateClassifier) Classify(err error) (Code, bool) {
+	if err == nil {
+		return CodeNone, false
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch pgErr.SQLState() {
+		case "40001":
+			return CodePGSerialization, true
+		case "40P01":
+			return CodePGDeadlock, true
+		}
+		if code, ok := c.extraSQLStates[pgErr.SQLState()]; ok {
+			return code, true
+		}
+	}
+
+	var myErr *mysql.MySQLError
+	if errors.As(err, &myErr) {
+		switch myErr.Number {
+		case 1213:
+			return CodeMySQLDeadlock, true
+		case 1205:
+			if c.retryLockTimeout {
+				return CodeMySQLLockTimeout, true
+			}
+			return CodeNone, false
+		}
+		if code, ok := c.extraMySQLErrors[myErr.Number]; ok {
+			return code, true
+		}
+	}
+
+	return CodeNone, false
+}
+
+// Backoff computes a full-jitter exponential delay for the given attempt
+// (0-indexed): random(0, min(cap, base*2^attempt)).
+func Backoff(attempt int, base, cap time.Duration) time.Duration {
+	if base <= 0 {
+		base = 20 * time.Millisecond
+	}
+	if cap <= 0 {
+		cap = 2 * time.Second
+	}
+	d := base << attempt // base * 2^attempt
+	if d <= 0 || d > cap {
+		d = cap
+	}
+	return time.Duration(rand.Int63n(int64(d) + 1))
+}
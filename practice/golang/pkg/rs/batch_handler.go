@@ -0,0 +1,73 @@
+package rs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	redis "github.com/redis/go-redis/v9"
+)
+
+// BatchMemberReadTaskFunc processes one decoded BatchMemberReadTask read off
+// a stream message.
+type BatchMemberReadTaskFunc func(ctx context.Context, msg redis.XMessage, batch *BatchMemberReadTask) error
+
+// BatchMemberReadTaskHandler adapts fn into a Handler, decoding the
+// "kind"/"payload" fields PublishBatchMemberReadTasks writes. Messages of
+// any other kind are routed to fallback if provided, or otherwise acked
+// without further processing.
+//
+// New kinds should prefer HandlerRegistry/RegisterHandler instead of
+// writing another one-off adapter like this; it predates that registry and
+// stays only because it's already wired up in cmd/rs-consumer.
+func BatchMemberReadTaskHandler(fn BatchMemberReadTaskFunc, fallback Handler) Handler {
+	return func(ctx context.Context, msg redis.XMessage) error {
+		kind, _ := stringField(msg.Values["kind"])
+		if kind != "BatchMemberReadTask" {
+			if fallback != nil {
+				return fallback(ctx, msg)
+			}
+			return nil
+		}
+
+		payload, err := bytesField(msg.Values["payload"])
+		if err != nil {
+			return fmt.Errorf("rs: message %s: %w", msg.ID, err)
+		}
+
+		var batch BatchMemberReadTask
+		if err := json.Unmarshal(payload, &batch); err != nil {
+			return fmt.Errorf("rs: unmarshal BatchMemberReadTask for message %s: %w", msg.ID, err)
+		}
+		return fn(ctx, msg, &batch)
+	}
+}
+
+// stringField coerces a decoded stream field (string or []byte, as
+// go-redis returns them) to string.
+func stringField(v any) (string, bool) {
+	switch t := v.(type) {
+	case string:
+		return t, true
+	case []byte:
+		return string(t), true
+	default:
+		return "", false
+	}
+}
+
+// bytesField coerces a decoded stream field (string or []byte) to []byte,
+// erroring on a missing or unexpected-type field.
+func bytesField(v any) ([]byte, error) {
+	if v == nil {
+		return nil, fmt.Errorf("missing field")
+	}
+	switch t := v.(type) {
+	case string:
+		return []byte(t), nil
+	case []byte:
+		return t, nil
+	default:
+		return nil, fmt.Errorf("unexpected field type %T", v)
+	}
+}
@@ -0,0 +1,92 @@
+package rs
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// Codec serializes/deserializes stream message payloads. Encode picks the
+// "kind" tag a payload is published under (so producer and consumer agree
+// on it without the caller hard-coding a string at every call site);
+// Decode turns bytes back into a value given that same kind.
+type Codec interface {
+	Encode(v any) (payload []byte, kind string, err error)
+	Decode(payload []byte, kind string) (v any, err error)
+}
+
+// JSONCodec is the default Codec: payloads are plain JSON, and kind is
+// either the registered name for v's type (see RegisterKind) or, absent
+// that, the Go type's own name -- which is why existing kinds like
+// "BatchMemberReadTask" keep working without an explicit RegisterKind call.
+type JSONCodec struct {
+	mu    sync.RWMutex
+	types map[string]reflect.Type
+}
+
+// NewJSONCodec returns an empty JSONCodec. Call RegisterKind to pin a kind
+// to a type explicitly, or rely on the type name default.
+func NewJSONCodec() *JSONCodec {
+	return &JSONCodec{types: make(map[string]reflect.Type)}
+}
+
+// RegisterKind associates kind with the type of zero, so Decode knows what
+// to allocate for messages published under kind. zero may be a value
+// (MyTask{}) or a pointer (&MyTask{}, even typed-nil (*MyTask)(nil)); the
+// same shape comes back out of Decode.
+func (c *JSONCodec) RegisterKind(kind string, zero any) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.types[kind] = reflect.TypeOf(zero)
+}
+
+func (c *JSONCodec) Encode(v any) ([]byte, string, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, "", err
+	}
+	return b, c.kindOf(v), nil
+}
+
+func (c *JSONCodec) kindOf(v any) string {
+	t := reflect.TypeOf(v)
+
+	c.mu.RLock()
+	for kind, rt := range c.types {
+		if rt == t {
+			c.mu.RUnlock()
+			return kind
+		}
+	}
+	c.mu.RUnlock()
+
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.Name()
+}
+
+func (c *JSONCodec) Decode(payload []byte, kind string) (any, error) {
+	c.mu.RLock()
+	t, ok := c.types[kind]
+	c.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("rs: no type registered for kind %q", kind)
+	}
+
+	isPtr := t.Kind() == reflect.Ptr
+	elemType := t
+	if isPtr {
+		elemType = t.Elem()
+	}
+
+	ptr := reflect.New(elemType)
+	if err := json.Unmarshal(payload, ptr.Interface()); err != nil {
+		return nil, err
+	}
+	if isPtr {
+		return ptr.Interface(), nil
+	}
+	return ptr.Elem().Interface(), nil
+}
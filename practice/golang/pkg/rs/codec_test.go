@@ -0,0 +1,85 @@
+package rs
+
+import (
+	"reflect"
+	"testing"
+)
+
+type codecTestTask struct {
+	ID   int64
+	Name string
+}
+
+func TestJSONCodec_EncodeDecodeRoundTrip_RegisteredValue(t *testing.T) {
+	c := NewJSONCodec()
+	c.RegisterKind("task", codecTestTask{})
+
+	want := codecTestTask{ID: 7, Name: "send-email"}
+	payload, kind, err := c.Encode(want)
+	if err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+	if kind != "task" {
+		t.Fatalf("Encode() kind = %q, want %q", kind, "task")
+	}
+
+	got, err := c.Decode(payload, kind)
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Decode() = %+v, want %+v", got, want)
+	}
+}
+
+func TestJSONCodec_EncodeDecodeRoundTrip_RegisteredPointer(t *testing.T) {
+	c := NewJSONCodec()
+	c.RegisterKind("task-ptr", (*codecTestTask)(nil))
+
+	want := &codecTestTask{ID: 9, Name: "send-sms"}
+	payload, kind, err := c.Encode(want)
+	if err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+
+	got, err := c.Decode(payload, kind)
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	gotPtr, ok := got.(*codecTestTask)
+	if !ok {
+		t.Fatalf("Decode() returned %T, want *codecTestTask", got)
+	}
+	if !reflect.DeepEqual(gotPtr, want) {
+		t.Errorf("Decode() = %+v, want %+v", gotPtr, want)
+	}
+}
+
+func TestJSONCodec_EncodeUnregisteredUsesTypeName(t *testing.T) {
+	c := NewJSONCodec()
+	_, kind, err := c.Encode(codecTestTask{ID: 1})
+	if err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+	if kind != "codecTestTask" {
+		t.Errorf("Encode() kind = %q, want %q", kind, "codecTestTask")
+	}
+}
+
+func TestJSONCodec_EncodeUnregisteredPointerUsesElemTypeName(t *testing.T) {
+	c := NewJSONCodec()
+	_, kind, err := c.Encode(&codecTestTask{ID: 1})
+	if err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+	if kind != "codecTestTask" {
+		t.Errorf("Encode() kind = %q, want %q", kind, "codecTestTask")
+	}
+}
+
+func TestJSONCodec_DecodeUnknownKindErrors(t *testing.T) {
+	c := NewJSONCodec()
+	if _, err := c.Decode([]byte(`{}`), "nope"); err == nil {
+		t.Error("Decode() with unregistered kind: expected error, got nil")
+	}
+}
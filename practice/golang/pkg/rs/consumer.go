@@ -2,19 +2,44 @@ package rs
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sync"
 	"time"
 
+	"github.com/cespare/xxhash/v2"
 	redis "github.com/redis/go-redis/v9"
 )
 
-// Handler processes a single message; return error to leave it pending for retry/claim.
+// Handler processes a single message; return error to leave it pending for
+// retry/claim. Return RetryAfter(d) instead of a bare error when the wait
+// before the next claim attempt should be d rather than the usual
+// claimMinIdle/WithBackoff schedule.
 type Handler func(ctx context.Context, msg redis.XMessage) error
 
+// RetryAfterError is returned by RetryAfter. Consumer.Run detects it with
+// errors.As and holds the message back from the next XCLAIM pass until its
+// pending idle time exceeds After, overriding claimMinIdle/WithBackoff for
+// that one message.
+type RetryAfterError struct {
+	After time.Duration
+}
+
+func (e *RetryAfterError) Error() string {
+	return fmt.Sprintf("retry after %s", e.After)
+}
+
+// RetryAfter builds a Handler error that tells Consumer.Run to wait at least
+// d before reclaiming this message again, for failures whose retry timing is
+// known up front (e.g. a downstream rate limit) rather than a fixed backoff.
+func RetryAfter(d time.Duration) error {
+	return &RetryAfterError{After: d}
+}
+
 // Consumer reads from a concrete stream (already sharded), within a consumer group.
 // It handles group creation, reading, acking, and periodic claim of stale messages.
 type Consumer struct {
-	rdb            *redis.Client
+	rdb            redis.UniversalClient
 	stream         string
 	group          string
 	consumer       string
@@ -23,6 +48,16 @@ type Consumer struct {
 	claimEvery     time.Duration
 	claimMinIdle   time.Duration
 	claimScanCount int64
+	maxDeliveries  int64
+	dlqSuffix      string
+	dlqStream      string
+	backoff        func(attempt int) time.Duration
+	observer       Observer
+	concurrency    int
+	orderKey       func(redis.XMessage) string
+
+	mu        sync.Mutex
+	notBefore map[string]time.Time
 }
 
 type ConsumerOption func(*Consumer)
@@ -35,8 +70,64 @@ func WithClaimMinIdle(d time.Duration) ConsumerOption {
 }
 func WithClaimScanCount(n int64) ConsumerOption { return func(c *Consumer) { c.claimScanCount = n } }
 
-// NewShardedConsumer builds a consumer for streamBase:shard.
-func NewShardedConsumer(rdb *redis.Client, streamBase string, shard int, group, consumer string, opts ...ConsumerOption) *Consumer {
+// WithMaxDeliveries sets how many times an entry may be reclaimed (XPENDING
+// delivery count) before it's moved to the dead-letter stream instead of
+// being claimed again. 0 (the default) disables dead-lettering.
+func WithMaxDeliveries(n int64) ConsumerOption { return func(c *Consumer) { c.maxDeliveries = n } }
+
+// WithDeadLetterSuffix overrides the default ":dlq" suffix appended to the
+// stream name to build the dead-letter stream name.
+func WithDeadLetterSuffix(suffix string) ConsumerOption {
+	return func(c *Consumer) { c.dlqSuffix = suffix }
+}
+
+// WithDeadLetter sets both the dead-letter stream and the delivery count it
+// kicks in at, in one call: streamName overrides the default
+// stream+dlqSuffix naming (see DeadLetterStream), and maxDeliveries is
+// equivalent to WithMaxDeliveries. Prefer this over the two separate options
+// when the dead-letter stream isn't simply the source stream plus a suffix,
+// e.g. when several shards should dead-letter onto one shared stream.
+func WithDeadLetter(streamName string, maxDeliveries int) ConsumerOption {
+	return func(c *Consumer) {
+		c.dlqStream = streamName
+		c.maxDeliveries = int64(maxDeliveries)
+	}
+}
+
+// WithBackoff overrides the fixed claimMinIdle wait with a per-attempt
+// schedule: fn is called with the message's current delivery count (1 on its
+// first redelivery check) and returns how long it must have been idle before
+// Run will XCLAIM it again.
+func WithBackoff(fn func(attempt int) time.Duration) ConsumerOption {
+	return func(c *Consumer) { c.backoff = fn }
+}
+
+// WithConcurrency runs n worker goroutines per shard instead of handling
+// messages one at a time. Without WithOrderKey, messages are round-robined
+// across workers with no ordering guarantee between them. n <= 1 (the
+// default) keeps the original serial behavior.
+func WithConcurrency(n int) ConsumerOption {
+	return func(c *Consumer) { c.concurrency = n }
+}
+
+// WithOrderKey routes every message to a worker chosen by hashing fn(msg)
+// mod the concurrency set by WithConcurrency, so messages sharing a key
+// (e.g. the same channel_id) are always handled by the same worker and
+// therefore processed in the order Run reads them. Ignored when
+// concurrency <= 1.
+func WithOrderKey(fn func(redis.XMessage) string) ConsumerOption {
+	return func(c *Consumer) { c.orderKey = fn }
+}
+
+// NewShardedConsumer builds a consumer for streamBase:shard. rdb is a
+// redis.UniversalClient so the same Consumer runs unmodified against a
+// standalone redis.Client, a Sentinel-backed redis.FailoverClient, or a
+// redis.ClusterClient -- see cmd/rs-consumer's -redis-url parsing. Under
+// Cluster, streamBase:shard keys aren't forced into one hash slot, so
+// XREADGROUP/XCLAIM/XPENDING for a given shard still address a single
+// stream key and therefore a single slot; nothing here depends on
+// cross-slot access.
+func NewShardedConsumer(rdb redis.UniversalClient, streamBase string, shard int, group, consumer string, opts ...ConsumerOption) *Consumer {
 	stream := fmt.Sprintf("%s:%d", streamBase, shard)
 	c := &Consumer{
 		rdb:            rdb,
@@ -48,6 +139,8 @@ func NewShardedConsumer(rdb *redis.Client, streamBase string, shard int, group,
 		claimEvery:     30 * time.Second,
 		claimMinIdle:   60 * time.Second,
 		claimScanCount: 50,
+		dlqSuffix:      ":dlq",
+		observer:       noopObserver{},
 	}
 	for _, opt := range opts {
 		opt(c)
@@ -60,7 +153,140 @@ func (c *Consumer) EnsureGroup(ctx context.Context) error {
 	return c.rdb.XGroupCreateMkStream(ctx, c.stream, c.group, "$").Err()
 }
 
-// Run starts the read/ack/claim loop until ctx is done.
+// DeadLetterStream returns the stream name failed entries are moved to once
+// they exceed WithMaxDeliveries: the WithDeadLetter stream name if set,
+// otherwise stream+dlqSuffix.
+func (c *Consumer) DeadLetterStream() string {
+	if c.dlqStream != "" {
+		return c.dlqStream
+	}
+	return c.stream + c.dlqSuffix
+}
+
+// moveToDeadLetter copies the pending entry's fields (re-read via XRange,
+// since XPENDING doesn't carry the message body) onto DeadLetterStream()
+// along with the original id, delivery count, consumer, and cause, then acks
+// it on the main stream so it stops showing up in XPENDING.
+func (c *Consumer) moveToDeadLetter(ctx context.Context, p redis.XPendingExt, cause error) error {
+	entries, err := c.rdb.XRange(ctx, c.stream, p.ID, p.ID).Result()
+	if err != nil {
+		return fmt.Errorf("read original entry %s: %w", p.ID, err)
+	}
+
+	values := map[string]any{}
+	if len(entries) > 0 {
+		for k, v := range entries[0].Values {
+			values[k] = v
+		}
+	}
+	values["dlq_original_id"] = p.ID
+	values["dlq_delivery_count"] = p.RetryCount
+	values["dlq_consumer"] = c.consumer
+	if cause != nil {
+		values["dlq_error"] = cause.Error()
+	}
+
+	if _, err := c.rdb.XAdd(ctx, &redis.XAddArgs{Stream: c.DeadLetterStream(), Values: values}).Result(); err != nil {
+		return fmt.Errorf("publish to dead letter stream: %w", err)
+	}
+	c.forget(p.ID)
+	return c.rdb.XAck(ctx, c.stream, c.group, p.ID).Err()
+}
+
+// noteHandlerError records, for a RetryAfter failure, the time before which
+// Run's claim pass must leave msgID alone. Non-RetryAfter errors are left to
+// the usual claimMinIdle/WithBackoff schedule.
+func (c *Consumer) noteHandlerError(msgID string, err error) {
+	var ra *RetryAfterError
+	if !errors.As(err, &ra) {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.notBefore == nil {
+		c.notBefore = make(map[string]time.Time)
+	}
+	c.notBefore[msgID] = time.Now().Add(ra.After)
+}
+
+// readyToReclaim reports whether msgID's RetryAfter deadline, if any, has
+// passed. It clears the deadline once consulted so the map doesn't grow
+// unboundedly across the consumer's lifetime.
+func (c *Consumer) readyToReclaim(msgID string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	until, ok := c.notBefore[msgID]
+	if !ok {
+		return true
+	}
+	if time.Now().Before(until) {
+		return false
+	}
+	delete(c.notBefore, msgID)
+	return true
+}
+
+// forget drops any RetryAfter deadline recorded for msgID, e.g. once it's
+// acked or dead-lettered and won't be reclaimed again.
+func (c *Consumer) forget(msgID string) {
+	c.mu.Lock()
+	delete(c.notBefore, msgID)
+	c.mu.Unlock()
+}
+
+// handleAndAck runs handler on msg, records the RetryAfter/Observer
+// bookkeeping common to first delivery and reclaim, and XACKs on success.
+func (c *Consumer) handleAndAck(ctx context.Context, handler Handler, msg redis.XMessage) {
+	start := time.Now()
+	err := handler(ctx, msg)
+	c.observer.OnHandle(msg.ID, err, time.Since(start))
+	if err != nil {
+		// leave pending for retry/claim
+		c.noteHandlerError(msg.ID, err)
+		return
+	}
+	c.forget(msg.ID)
+	if err := c.rdb.XAck(ctx, c.stream, c.group, msg.ID).Err(); err == nil {
+		c.observer.OnAck(msg.ID)
+	}
+}
+
+// worker drains ch, calling handleAndAck for each message, until ch is
+// closed. Run closes every worker channel and waits for its worker to drain
+// before returning, so in-flight handlers finish instead of being abandoned.
+func (c *Consumer) worker(ctx context.Context, handler Handler, ch <-chan redis.XMessage, wg *sync.WaitGroup) {
+	defer wg.Done()
+	for msg := range ch {
+		c.handleAndAck(ctx, handler, msg)
+	}
+}
+
+// dispatch routes msg to a worker channel (hashing WithOrderKey's key mod
+// len(workers) when set, round-robin otherwise) when concurrency > 1, or
+// runs it inline otherwise. next is the round-robin cursor for the
+// no-order-key case.
+func (c *Consumer) dispatch(ctx context.Context, handler Handler, workers []chan redis.XMessage, next *int, msg redis.XMessage) {
+	if len(workers) == 0 {
+		c.handleAndAck(ctx, handler, msg)
+		return
+	}
+	idx := 0
+	if c.orderKey != nil {
+		idx = int(xxhash.Sum64String(c.orderKey(msg)) % uint64(len(workers)))
+	} else {
+		idx = *next % len(workers)
+		*next++
+	}
+	select {
+	case workers[idx] <- msg:
+	case <-ctx.Done():
+	}
+}
+
+// Run starts the read/ack/claim loop until ctx is done. With WithConcurrency
+// set above 1, messages are fanned out to worker goroutines (ordered per
+// WithOrderKey's key, if set) instead of being handled one at a time; Run
+// drains every worker's in-flight and buffered messages before returning.
 func (c *Consumer) Run(ctx context.Context, handler Handler) error {
 	// try to create group (ignore already-exists)
 	if err := c.EnsureGroup(ctx); err != nil {
@@ -69,6 +295,24 @@ func (c *Consumer) Run(ctx context.Context, handler Handler) error {
 		}
 	}
 
+	var workers []chan redis.XMessage
+	var wg sync.WaitGroup
+	if c.concurrency > 1 {
+		workers = make([]chan redis.XMessage, c.concurrency)
+		for i := range workers {
+			workers[i] = make(chan redis.XMessage, c.batch)
+			wg.Add(1)
+			go c.worker(ctx, handler, workers[i], &wg)
+		}
+		defer func() {
+			for _, ch := range workers {
+				close(ch)
+			}
+			wg.Wait()
+		}()
+	}
+	next := 0
+
 	lastClaim := time.Now()
 	for ctx.Err() == nil {
 		streams, err := c.rdb.XReadGroup(ctx, &redis.XReadGroupArgs{
@@ -84,12 +328,15 @@ func (c *Consumer) Run(ctx context.Context, handler Handler) error {
 		}
 		if len(streams) > 0 {
 			for _, s := range streams {
+				n := len(s.Messages)
+				var lag time.Duration
+				if n > 0 {
+					lastID := s.Messages[n-1].ID
+					lag = time.Since(time.UnixMilli(streamIDMillis(lastID)))
+				}
+				c.observer.OnRead(n, lag)
 				for _, msg := range s.Messages {
-					if err := handler(ctx, msg); err != nil {
-						// leave pending for retry/claim
-						continue
-					}
-					_ = c.rdb.XAck(ctx, c.stream, c.group, msg.ID).Err()
+					c.dispatch(ctx, handler, workers, &next, msg)
 				}
 			}
 		}
@@ -105,21 +352,47 @@ func (c *Consumer) Run(ctx context.Context, handler Handler) error {
 				Count:  c.claimScanCount,
 			}).Result()
 			if err == nil {
-				var ids []string
+				// Group eligible ids by the requiredIdle threshold that
+				// admitted them, since XCLAIM enforces MinIdle server-side:
+				// passing the fixed c.claimMinIdle here would silently
+				// re-reject any id a faster WithBackoff schedule just
+				// cleared on the Go side.
+				idsByMinIdle := map[time.Duration][]string{}
 				for _, p := range pending {
-					if p.Idle >= c.claimMinIdle {
-						ids = append(ids, p.ID)
+					requiredIdle := c.claimMinIdle
+					if c.backoff != nil {
+						requiredIdle = c.backoff(int(p.RetryCount))
 					}
+					if p.Idle < requiredIdle {
+						continue
+					}
+					if c.maxDeliveries > 0 && p.RetryCount > c.maxDeliveries {
+						_ = c.moveToDeadLetter(ctx, p, fmt.Errorf("exceeded max deliveries (%d)", c.maxDeliveries))
+						continue
+					}
+					if !c.readyToReclaim(p.ID) {
+						continue
+					}
+					idsByMinIdle[requiredIdle] = append(idsByMinIdle[requiredIdle], p.ID)
 				}
-				if len(ids) > 0 {
-					_, _ = c.rdb.XClaim(ctx, &redis.XClaimArgs{
+				claimedCount := 0
+				for minIdle, ids := range idsByMinIdle {
+					claimed, err := c.rdb.XClaim(ctx, &redis.XClaimArgs{
 						Stream:   c.stream,
 						Group:    c.group,
 						Consumer: c.consumer,
-						MinIdle:  c.claimMinIdle,
+						MinIdle:  minIdle,
 						Messages: ids,
 					}).Result()
+					if err != nil {
+						continue
+					}
+					claimedCount += len(claimed)
+					for _, msg := range claimed {
+						c.dispatch(ctx, handler, workers, &next, msg)
+					}
 				}
+				c.observer.OnClaim(claimedCount)
 			}
 		}
 	}
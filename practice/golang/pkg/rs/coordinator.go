@@ -0,0 +1,267 @@
+package rs
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cespare/xxhash/v2"
+	redis "github.com/redis/go-redis/v9"
+)
+
+// Coordinator replaces a fixed node-id/total-nodes shard assignment with a
+// live cluster membership layer: nodes heartbeat a Redis key under
+// "rs:members:<group>:<peerID>", and every node independently recomputes
+// who owns which shard by rendezvous-hashing (HRW) the current member list
+// against each shard key. Because every node sees the same member list and
+// the same deterministic hash, they agree on ownership without any extra
+// coordination round-trip.
+//
+// On an ownership change, Coordinator cancels the Consumer.Run goroutines
+// for shards it no longer owns and starts NewShardedConsumer ones for
+// shards it gained. A departing node does nothing special beyond stopping
+// -- the new owner's own XPENDING/XCLAIM loop picks up whatever was left
+// in-flight once claimMinIdle elapses, exactly as it would for a crashed
+// node.
+type Coordinator struct {
+	rdb         redis.UniversalClient
+	streamBase  string
+	totalShards int
+	group       string
+	peerID      string
+	handler     Handler
+
+	heartbeatEvery time.Duration
+	memberTTL      time.Duration
+	rebalanceEvery time.Duration
+	consumerOpts   []ConsumerOption
+
+	mu      sync.Mutex
+	running map[int]context.CancelFunc
+}
+
+type CoordinatorOption func(*Coordinator)
+
+// WithHeartbeatEvery sets how often the node refreshes its membership key.
+// Defaults to memberTTL/3.
+func WithHeartbeatEvery(d time.Duration) CoordinatorOption {
+	return func(c *Coordinator) { c.heartbeatEvery = d }
+}
+
+// WithMemberTTL sets how long a missed heartbeat is tolerated before peers
+// stop considering this node a member. Defaults to 15s.
+func WithMemberTTL(d time.Duration) CoordinatorOption {
+	return func(c *Coordinator) { c.memberTTL = d }
+}
+
+// WithRebalanceEvery sets how often the node re-scans membership and
+// recomputes its owned shard set, independent of the heartbeat cadence.
+// Defaults to 5s.
+func WithRebalanceEvery(d time.Duration) CoordinatorOption {
+	return func(c *Coordinator) { c.rebalanceEvery = d }
+}
+
+// WithConsumerOptions passes opts through to every NewShardedConsumer the
+// Coordinator starts.
+func WithConsumerOptions(opts ...ConsumerOption) CoordinatorOption {
+	return func(c *Coordinator) { c.consumerOpts = opts }
+}
+
+// NewCoordinator builds a Coordinator that owns a subset of
+// [0, totalShards) shards of streamBase:{shard}, dynamically rebalanced
+// across every live peer sharing group. handler processes messages for
+// every shard this node owns; it must be safe for concurrent use, since one
+// goroutine runs per owned shard.
+func NewCoordinator(rdb redis.UniversalClient, streamBase string, totalShards int, group, peerID string, handler Handler, opts ...CoordinatorOption) *Coordinator {
+	c := &Coordinator{
+		rdb:            rdb,
+		streamBase:     streamBase,
+		totalShards:    totalShards,
+		group:          group,
+		peerID:         peerID,
+		handler:        handler,
+		memberTTL:      15 * time.Second,
+		rebalanceEvery: 5 * time.Second,
+		running:        make(map[int]context.CancelFunc),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.heartbeatEvery <= 0 {
+		c.heartbeatEvery = c.memberTTL / 3
+	}
+	return c
+}
+
+func (c *Coordinator) memberKey(peer string) string {
+	return fmt.Sprintf("rs:members:%s:%s", c.group, peer)
+}
+
+func (c *Coordinator) memberPrefix() string {
+	return fmt.Sprintf("rs:members:%s:", c.group)
+}
+
+// Run heartbeats this node's membership and rebalances owned shards until
+// ctx is done, then stops every Consumer it started and deregisters the
+// membership key so peers don't wait out memberTTL to pick up the shards.
+func (c *Coordinator) Run(ctx context.Context) error {
+	if err := c.heartbeat(ctx); err != nil {
+		return fmt.Errorf("rs: initial coordinator heartbeat: %w", err)
+	}
+	if err := c.rebalance(ctx); err != nil {
+		return fmt.Errorf("rs: initial coordinator rebalance: %w", err)
+	}
+
+	hb := time.NewTicker(c.heartbeatEvery)
+	defer hb.Stop()
+	rebal := time.NewTicker(c.rebalanceEvery)
+	defer rebal.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			c.stopAll()
+			c.rdb.Del(context.Background(), c.memberKey(c.peerID))
+			return ctx.Err()
+		case <-hb.C:
+			_ = c.heartbeat(ctx)
+		case <-rebal.C:
+			_ = c.rebalance(ctx)
+		}
+	}
+}
+
+// heartbeat claims this node's membership key on first call and refreshes
+// its TTL afterward.
+func (c *Coordinator) heartbeat(ctx context.Context) error {
+	key := c.memberKey(c.peerID)
+	created, err := c.rdb.SetNX(ctx, key, "1", c.memberTTL).Result()
+	if err != nil {
+		return err
+	}
+	if created {
+		return nil
+	}
+	return c.rdb.PExpire(ctx, key, c.memberTTL).Err()
+}
+
+// peers returns the sorted set of currently live member ids.
+func (c *Coordinator) peers(ctx context.Context) ([]string, error) {
+	prefix := c.memberPrefix()
+	var (
+		peers []string
+		mu    sync.Mutex
+	)
+	scan := func(ctx context.Context, rdb redis.UniversalClient) error {
+		iter := rdb.Scan(ctx, 0, prefix+"*", 100).Iterator()
+		for iter.Next(ctx) {
+			mu.Lock()
+			peers = append(peers, strings.TrimPrefix(iter.Val(), prefix))
+			mu.Unlock()
+		}
+		return iter.Err()
+	}
+
+	// SCAN only walks the keyspace of whichever single node the client
+	// routes it to. On a standalone/Sentinel client that's the only node
+	// there is, but on a ClusterClient membership keys are sharded across
+	// every master, so an unkeyed SCAN there would silently return only a
+	// partial, node-local peer list. ForEachMaster scans every shard and
+	// merges the results so rebalancing sees the whole membership.
+	if cc, ok := c.rdb.(*redis.ClusterClient); ok {
+		if err := cc.ForEachMaster(ctx, func(ctx context.Context, master *redis.Client) error {
+			return scan(ctx, master)
+		}); err != nil {
+			return nil, err
+		}
+	} else if err := scan(ctx, c.rdb); err != nil {
+		return nil, err
+	}
+
+	sort.Strings(peers)
+	return peers, nil
+}
+
+// ownerOf picks the peer maximizing xxhash64(peer + ":" + shardKey) -- HRW
+// rendezvous hashing, so adding or removing one peer only reassigns the
+// shards that peer directly won or lost, not the whole keyspace.
+func ownerOf(peers []string, shardKey string) string {
+	if len(peers) == 0 {
+		return ""
+	}
+	best := peers[0]
+	var bestScore uint64
+	for _, p := range peers {
+		score := xxhash.Sum64String(p + ":" + shardKey)
+		if score > bestScore {
+			bestScore = score
+			best = p
+		}
+	}
+	return best
+}
+
+// rebalance recomputes this node's owned shard set against the current
+// member list and starts/stops Consumers to match.
+func (c *Coordinator) rebalance(ctx context.Context) error {
+	peers, err := c.peers(ctx)
+	if err != nil {
+		return fmt.Errorf("rs: list coordinator members: %w", err)
+	}
+
+	owned := make(map[int]bool, c.totalShards)
+	for shard := 0; shard < c.totalShards; shard++ {
+		shardKey := fmt.Sprintf("%s:%d", c.streamBase, shard)
+		if ownerOf(peers, shardKey) == c.peerID {
+			owned[shard] = true
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for shard, cancel := range c.running {
+		if !owned[shard] {
+			cancel()
+			delete(c.running, shard)
+		}
+	}
+	for shard := range owned {
+		if _, ok := c.running[shard]; ok {
+			continue
+		}
+		shardCtx, cancel := context.WithCancel(ctx)
+		consumer := NewShardedConsumer(c.rdb, c.streamBase, shard, c.group, c.peerID, c.consumerOpts...)
+		c.running[shard] = cancel
+		go func(shard int, consumer *Consumer, ctx context.Context) {
+			_ = consumer.Run(ctx, c.handler)
+		}(shard, consumer, shardCtx)
+	}
+	return nil
+}
+
+// stopAll cancels every Consumer this node currently runs.
+func (c *Coordinator) stopAll() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for shard, cancel := range c.running {
+		cancel()
+		delete(c.running, shard)
+	}
+}
+
+// OwnedShards returns the shards this node currently runs a Consumer for,
+// for tests/introspection.
+func (c *Coordinator) OwnedShards() []int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	shards := make([]int, 0, len(c.running))
+	for shard := range c.running {
+		shards = append(shards, shard)
+	}
+	sort.Ints(shards)
+	return shards
+}
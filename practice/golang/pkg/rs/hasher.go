@@ -2,6 +2,8 @@ package rs
 
 import (
 	"fmt"
+	"sort"
+	"sync"
 
 	"github.com/cespare/xxhash/v2"
 )
@@ -39,11 +41,112 @@ func (XXHash64Hasher) PartitionID(id int64, parts int) int {
 
 func (XXHash64Hasher) Name() string { return "xxhash64" }
 
-// NewHasherFromString returns a hasher by name: "mod" or "xxhash64".
+// RendezvousHasher picks the shard bucket that maximizes
+// xxhash64(id || bucket_i) (highest random weight, a.k.a. HRW hashing).
+// Unlike a plain mod/xxhash64%parts scheme, changing parts only remaps
+// roughly 1/parts of the keyspace instead of nearly all of it, which makes
+// it safe to reshuffle Producer/Consumer partition counts at runtime.
+type RendezvousHasher struct{}
+
+func (RendezvousHasher) PartitionID(id int64, parts int) int {
+	if parts <= 0 {
+		return 0
+	}
+	best := 0
+	var bestScore uint64
+	for i := 0; i < parts; i++ {
+		score := xxhash.Sum64String(fmt.Sprintf("%d:%d", id, i))
+		if score > bestScore {
+			bestScore = score
+			best = i
+		}
+	}
+	return best
+}
+
+func (RendezvousHasher) Name() string { return "rendezvous" }
+
+// ringEntry is one virtual node on a ConsistentRingHasher's ring.
+type ringEntry struct {
+	hash  uint64
+	shard int
+}
+
+// ConsistentRingHasher keeps a sorted ring of virtual nodes per shard count
+// and maps an id to its owning shard by binary-searching the ring for the
+// id's hash. Like RendezvousHasher, growing or shrinking parts only moves
+// a small fraction of keys instead of redistributing all traffic; the ring
+// form trades RendezvousHasher's O(parts)-per-lookup cost for an
+// O(vnodes*parts) one-time build per distinct parts value.
+type ConsistentRingHasher struct {
+	// VNodes is the number of virtual nodes per shard. Defaults to 128.
+	VNodes int
+
+	mu    sync.Mutex
+	rings map[int][]ringEntry // cached ring, keyed by parts
+}
+
+// NewConsistentRingHasher builds a ConsistentRingHasher with the default
+// vnode count (128 per shard).
+func NewConsistentRingHasher() *ConsistentRingHasher {
+	return &ConsistentRingHasher{VNodes: 128, rings: make(map[int][]ringEntry)}
+}
+
+func (h *ConsistentRingHasher) PartitionID(id int64, parts int) int {
+	if parts <= 0 {
+		return 0
+	}
+	ring := h.ringFor(parts)
+	key := xxhash.Sum64String(fmt.Sprintf("%d", id))
+	idx := sort.Search(len(ring), func(i int) bool { return ring[i].hash >= key })
+	if idx == len(ring) {
+		idx = 0
+	}
+	return ring[idx].shard
+}
+
+func (h *ConsistentRingHasher) Name() string { return "ring" }
+
+// ringFor returns the cached ring for parts shards, building and caching it
+// on first use.
+func (h *ConsistentRingHasher) ringFor(parts int) []ringEntry {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if ring, ok := h.rings[parts]; ok {
+		return ring
+	}
+
+	vnodes := h.VNodes
+	if vnodes <= 0 {
+		vnodes = 128
+	}
+	ring := make([]ringEntry, 0, parts*vnodes)
+	for shard := 0; shard < parts; shard++ {
+		for v := 0; v < vnodes; v++ {
+			key := fmt.Sprintf("shard-%d-v%d", shard, v)
+			ring = append(ring, ringEntry{hash: xxhash.Sum64String(key), shard: shard})
+		}
+	}
+	sort.Slice(ring, func(i, j int) bool { return ring[i].hash < ring[j].hash })
+
+	if h.rings == nil {
+		h.rings = make(map[int][]ringEntry)
+	}
+	h.rings[parts] = ring
+	return ring
+}
+
+// NewHasherFromString returns a hasher by name: "mod", "xxhash64",
+// "rendezvous", or "ring".
 func NewHasherFromString(name string) Hasher {
 	switch name {
 	case "xxhash64":
 		return XXHash64Hasher{}
+	case "rendezvous":
+		return RendezvousHasher{}
+	case "ring":
+		return NewConsistentRingHasher()
 	default:
 		return ModHasher{}
 	}
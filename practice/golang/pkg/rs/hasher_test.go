@@ -0,0 +1,97 @@
+package rs
+
+import "testing"
+
+func TestRendezvousHasher_PartsNonPositive(t *testing.T) {
+	h := RendezvousHasher{}
+	for _, parts := range []int{0, -1, -5} {
+		if got := h.PartitionID(42, parts); got != 0 {
+			t.Errorf("PartitionID(42, %d) = %d, want 0", parts, got)
+		}
+	}
+}
+
+func TestRendezvousHasher_WithinRangeAndDeterministic(t *testing.T) {
+	h := RendezvousHasher{}
+	const parts = 8
+	for _, id := range []int64{0, 1, 2, 12345, -7} {
+		first := h.PartitionID(id, parts)
+		if first < 0 || first >= parts {
+			t.Fatalf("PartitionID(%d, %d) = %d, out of range", id, parts, first)
+		}
+		for i := 0; i < 5; i++ {
+			if got := h.PartitionID(id, parts); got != first {
+				t.Errorf("PartitionID(%d, %d) not deterministic: got %d, want %d", id, parts, got, first)
+			}
+		}
+	}
+}
+
+func TestRendezvousHasher_Name(t *testing.T) {
+	if got := (RendezvousHasher{}).Name(); got != "rendezvous" {
+		t.Errorf("Name() = %q, want %q", got, "rendezvous")
+	}
+}
+
+func TestConsistentRingHasher_PartsNonPositive(t *testing.T) {
+	h := NewConsistentRingHasher()
+	for _, parts := range []int{0, -1, -5} {
+		if got := h.PartitionID(42, parts); got != 0 {
+			t.Errorf("PartitionID(42, %d) = %d, want 0", parts, got)
+		}
+	}
+}
+
+func TestConsistentRingHasher_WithinRangeAndDeterministic(t *testing.T) {
+	h := NewConsistentRingHasher()
+	const parts = 8
+	for _, id := range []int64{0, 1, 2, 12345, -7} {
+		first := h.PartitionID(id, parts)
+		if first < 0 || first >= parts {
+			t.Fatalf("PartitionID(%d, %d) = %d, out of range", id, parts, first)
+		}
+		for i := 0; i < 5; i++ {
+			if got := h.PartitionID(id, parts); got != first {
+				t.Errorf("PartitionID(%d, %d) not deterministic: got %d, want %d", id, parts, got, first)
+			}
+		}
+	}
+}
+
+func TestConsistentRingHasher_RingCachedAcrossCalls(t *testing.T) {
+	h := NewConsistentRingHasher()
+	const parts = 4
+	before := h.PartitionID(99, parts)
+	if len(h.rings) != 1 {
+		t.Fatalf("expected ring for parts=%d to be cached, got %d cached rings", parts, len(h.rings))
+	}
+	after := h.PartitionID(99, parts)
+	if before != after {
+		t.Errorf("PartitionID(99, %d) changed across calls: %d then %d", parts, before, after)
+	}
+}
+
+func TestConsistentRingHasher_Name(t *testing.T) {
+	if got := NewConsistentRingHasher().Name(); got != "ring" {
+		t.Errorf("Name() = %q, want %q", got, "ring")
+	}
+}
+
+func TestNewHasherFromString(t *testing.T) {
+	cases := []struct {
+		name string
+		want string
+	}{
+		{"mod", "mod"},
+		{"xxhash64", "xxhash64"},
+		{"rendezvous", "rendezvous"},
+		{"ring", "ring"},
+		{"unknown", "mod"},
+		{"", "mod"},
+	}
+	for _, c := range cases {
+		if got := NewHasherFromString(c.name).Name(); got != c.want {
+			t.Errorf("NewHasherFromString(%q).Name() = %q, want %q", c.name, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package rs
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	redis "github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// idempotentPublishScript atomically does what a non-idempotent Publish
+// would do as a bare XAdd, but only the first time for a given idempotency
+// key: if the key is already recorded, it returns the stream entry id from
+// that first publish instead of writing a duplicate entry. Running the
+// existence check and the XADD+record in one EVAL closes the race a
+// separate "SET NX then XADD" pair would have between two producers
+// retrying the same logical publish concurrently.
+//
+// KEYS[1] = idempotency key
+// KEYS[2] = stream name
+// ARGV[1] = TTL in seconds for the idempotency key
+// ARGV[2:] = XADD field/value pairs
+var idempotentPublishScript = redis.NewScript(`
+local existing = redis.call('GET', KEYS[1])
+if existing then
+	return existing
+end
+local id = redis.call('XADD', KEYS[2], '*', unpack(ARGV, 2))
+redis.call('SET', KEYS[1], id, 'EX', ARGV[1])
+return id
+`)
+
+// publishOptions holds the per-call settings PublishOption mutates.
+type publishOptions struct {
+	idempotencyKey string
+}
+
+// PublishOption configures a single Publish/PublishToShard call.
+type PublishOption func(*publishOptions)
+
+// WithIdempotencyKey makes this publish a no-op beyond the first call for
+// key: repeat calls with the same key (e.g. retries after a timeout whose
+// XADD may or may not have landed) return the original call's stream entry
+// id instead of writing a second entry. Safe to call from a
+// dbx.AfterCommit hook, where the caller has no way to know whether a prior
+// attempt actually reached Redis. The key is scoped to this Producer's
+// stream base, so the same key used against a different Producer/stream
+// does not collide.
+func WithIdempotencyKey(key string) PublishOption {
+	return func(o *publishOptions) { o.idempotencyKey = key }
+}
+
+func (p *Producer) idempotencyRedisKey(key string) string {
+	return fmt.Sprintf("%s:idemp:%s", p.streamBase, key)
+}
+
+// publishIdempotent runs idempotentPublishScript against shard's stream,
+// recording the key for p.idempotencyTTL.
+func (p *Producer) publishIdempotent(ctx context.Context, shard int, values map[string]any, key string) (string, error) {
+	stream := p.StreamNameByShard(shard)
+	ctx, span := p.tracer.Start(ctx, "rs.publish", trace.WithAttributes(
+		attribute.Int("rs.shard", shard),
+		attribute.String("rs.stream", stream),
+		attribute.String("rs.idempotency_key", key),
+	))
+	defer span.End()
+
+	argv := make([]any, 0, 1+2*len(values))
+	ttl := p.idempotencyTTL
+	if ttl <= 0 {
+		ttl = defaultIdempotencyTTL
+	}
+	argv = append(argv, int64(ttl/time.Second))
+	for field, v := range values {
+		argv = append(argv, field, fmt.Sprint(v))
+	}
+
+	start := time.Now()
+	id, err := idempotentPublishScript.Run(ctx, p.rdb, []string{p.idempotencyRedisKey(key), stream}, argv...).Text()
+	p.recordPublish(ctx, shard, stream, time.Since(start), err)
+	if err != nil {
+		span.RecordError(err)
+	}
+	return id, err
+}
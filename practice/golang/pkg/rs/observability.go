@@ -0,0 +1,69 @@
+package rs
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/stoneMan1982/workexperience/practice/golang/pkg/rs"
+
+// WithTracer overrides the tracer Producer.publish uses for its rs.publish
+// spans. Defaults to otel.Tracer(instrumentationName), a no-op until the
+// caller registers a real SDK TracerProvider.
+func WithTracer(tracer trace.Tracer) ProducerOption {
+	return func(p *Producer) { p.tracer = tracer }
+}
+
+// WithMeter overrides the meter used for rs_publish_* metrics. Defaults to
+// otel.Meter(instrumentationName).
+func WithMeter(meter metric.Meter) ProducerOption {
+	return func(p *Producer) { p.setMeter(meter) }
+}
+
+// setMeter builds the instruments Producer.publish records to from meter.
+func (p *Producer) setMeter(meter metric.Meter) {
+	p.meter = meter
+
+	var err error
+	p.publishDuration, err = meter.Float64Histogram("rs_publish_duration_seconds",
+		metric.WithDescription("Duration of Producer XAdd publishes"), metric.WithUnit("s"))
+	if err != nil {
+		slog.Warn("rs: failed to create publish duration histogram", "err", err)
+	}
+	p.batchSize, err = meter.Int64Histogram("rs_publish_batch_size",
+		metric.WithDescription("Number of tasks per shard in PublishBatchMemberReadTasks"))
+	if err != nil {
+		slog.Warn("rs: failed to create publish batch size histogram", "err", err)
+	}
+	p.errorCounter, err = meter.Int64Counter("rs_publish_errors_total",
+		metric.WithDescription("Number of Producer publish failures, by stream"))
+	if err != nil {
+		slog.Warn("rs: failed to create publish error counter", "err", err)
+	}
+}
+
+// recordPublish records one rs.publish call's duration and, on failure,
+// increments rs_publish_errors_total.
+func (p *Producer) recordPublish(ctx context.Context, shard int, stream string, d time.Duration, err error) {
+	attrs := metric.WithAttributes(attribute.Int("rs.shard", shard), attribute.String("rs.stream", stream))
+	if p.publishDuration != nil {
+		p.publishDuration.Record(ctx, d.Seconds(), attrs)
+	}
+	if err != nil && p.errorCounter != nil {
+		p.errorCounter.Add(ctx, 1, attrs)
+	}
+}
+
+// recordBatchSize records how many tasks PublishBatchMemberReadTasks grouped
+// into one shard's batch.
+func (p *Producer) recordBatchSize(ctx context.Context, shard int, n int) {
+	if p.batchSize == nil {
+		return
+	}
+	p.batchSize.Record(ctx, int64(n), metric.WithAttributes(attribute.Int("rs.shard", shard)))
+}
@@ -0,0 +1,55 @@
+package rs
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Observer receives lifecycle events from Consumer.Run so metrics/tracing
+// can be plugged in without every caller re-instrumenting the read/ack/claim
+// loop itself. Implementations must be safe for concurrent use; Run calls
+// them inline on the hot path, so they should return quickly. See rs/prom
+// for a ready-made Prometheus implementation.
+type Observer interface {
+	// OnRead is called after each XREADGROUP poll, with the number of
+	// messages it returned (0 on a block timeout) and how stale the
+	// newest of them was when read.
+	OnRead(n int, lag time.Duration)
+	// OnHandle is called after handler(ctx, msg) returns, on both first
+	// delivery and reclaim, whether or not it returned an error.
+	OnHandle(msgID string, err error, dur time.Duration)
+	// OnAck is called after a message is successfully XACKed.
+	OnAck(msgID string)
+	// OnClaim is called after each periodic claim pass, with how many
+	// entries it reclaimed (0 if none were eligible).
+	OnClaim(n int)
+}
+
+// noopObserver is the default Observer; WithObserver overrides it.
+type noopObserver struct{}
+
+func (noopObserver) OnRead(int, time.Duration)             {}
+func (noopObserver) OnHandle(string, error, time.Duration) {}
+func (noopObserver) OnAck(string)                          {}
+func (noopObserver) OnClaim(int)                            {}
+
+// WithObserver plugs o into Consumer.Run's read/handle/ack/claim hooks.
+// Defaults to a no-op observer.
+func WithObserver(o Observer) ConsumerOption {
+	return func(c *Consumer) { c.observer = o }
+}
+
+// streamIDMillis extracts the millisecond-timestamp component of a Redis
+// stream ID ("<ms>-<seq>"), returning 0 if id can't be parsed.
+func streamIDMillis(id string) int64 {
+	ms, _, ok := strings.Cut(id, "-")
+	if !ok {
+		return 0
+	}
+	n, err := strconv.ParseInt(ms, 10, 64)
+	if err != nil {
+		return 0
+	}
+	return n
+}
@@ -0,0 +1,192 @@
+package rs
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/stoneMan1982/workexperience/practice/golang/pkg/dbx"
+	"github.com/uptrace/bun"
+)
+
+// OutboxRelay polls dbx.Outbox for unsent rows and, for each one, claims it
+// with SelectForUpdateSkipLocked (so multiple relay instances can run
+// concurrently without double-publishing), forwards it to Producer, and
+// marks it sent -- claim, publish, and mark-sent/mark-failed for a single
+// row all commit in one transaction, but each row gets its own transaction
+// rather than the whole batch sharing one. That way a later row's update
+// failure can't roll back the sent_at commits of rows already published
+// earlier in the same pass. A row is only marked sent after Publish
+// succeeds, and every publish carries a WithIdempotencyKey derived from the
+// row's id, so the at-least-once republish a crash between publish and
+// commit produces is a no-op on Producer's side instead of a duplicate
+// stream entry.
+type OutboxRelay struct {
+	db       *bun.DB
+	producer *Producer
+
+	batch       int
+	interval    time.Duration
+	maxAttempts int
+
+	wake chan struct{}
+}
+
+type OutboxRelayOption func(*OutboxRelay)
+
+// WithRelayBatch sets how many outbox rows are claimed per poll.
+func WithRelayBatch(n int) OutboxRelayOption { return func(r *OutboxRelay) { r.batch = n } }
+
+// WithRelayInterval sets how often the relay polls when not woken early.
+func WithRelayInterval(d time.Duration) OutboxRelayOption {
+	return func(r *OutboxRelay) { r.interval = d }
+}
+
+// WithRelayMaxAttempts caps how many times a row is retried before the
+// relay stops selecting it (it stays in the table with attempts at the
+// cap for operator inspection rather than being dropped).
+func WithRelayMaxAttempts(n int) OutboxRelayOption {
+	return func(r *OutboxRelay) { r.maxAttempts = n }
+}
+
+// NewOutboxRelay builds a relay that publishes dbx.Outbox rows through
+// producer.
+func NewOutboxRelay(db *bun.DB, producer *Producer, opts ...OutboxRelayOption) *OutboxRelay {
+	r := &OutboxRelay{
+		db:          db,
+		producer:    producer,
+		batch:       100,
+		interval:    time.Second,
+		maxAttempts: 10,
+		wake:        make(chan struct{}, 1),
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// Wake nudges the relay to poll immediately instead of waiting out its
+// interval. Call it from a dbx.AfterCommit hook right after EnqueueOutbox
+// to cut publish latency for the common case where the relay is otherwise
+// idle between polls.
+func (r *OutboxRelay) Wake() {
+	select {
+	case r.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run polls for unsent rows until ctx is done.
+func (r *OutboxRelay) Run(ctx context.Context) error {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+	for {
+		_ = r.drain(ctx) // errors are retried on the next pass
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		case <-r.wake:
+		}
+	}
+}
+
+// drain reads the ids of a batch of unsent rows and processes each one in
+// its own transaction via processOne.
+func (r *OutboxRelay) drain(ctx context.Context) error {
+	ids, err := r.claimBatch(ctx)
+	if err != nil {
+		return fmt.Errorf("claim outbox batch: %w", err)
+	}
+	var firstErr error
+	for _, id := range ids {
+		if err := r.processOne(ctx, id); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// claimBatch lists up to r.batch candidate row ids, oldest first. It reads
+// without locking -- each id is re-claimed (and actually locked, via
+// SelectForUpdateSkipLocked) individually in processOne, so a row another
+// relay instance grabs first between here and there is simply skipped
+// there instead of double-published.
+func (r *OutboxRelay) claimBatch(ctx context.Context) ([]int64, error) {
+	var rows []*dbx.Outbox
+	err := r.db.NewSelect().Model(&rows).Column("id").
+		Where("sent_at IS NULL AND attempts < ?", r.maxAttempts).
+		OrderExpr("created_at ASC").Limit(r.batch).Scan(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]int64, len(rows))
+	for i, row := range rows {
+		ids[i] = row.ID
+	}
+	return ids, nil
+}
+
+// processOne re-claims a single row with SKIP LOCKED, publishes it, and
+// records the outcome, all in one transaction: a publish failure commits
+// an attempts/last_error bump (so the row is retried, capped, next pass);
+// a publish success commits sent_at. Either way this row's outcome commits
+// independently of every other row processOne is called for.
+func (r *OutboxRelay) processOne(ctx context.Context, id int64) error {
+	return dbx.WithTx(ctx, r.db, nil, func(ctx context.Context, tx bun.Tx) error {
+		var rows []*dbx.Outbox
+		err := dbx.SelectForUpdateSkipLocked(ctx, tx, &rows, "outbox", "",
+			"id = ? AND sent_at IS NULL AND attempts < ?", "created_at ASC", 1, id, r.maxAttempts)
+		if err != nil {
+			return fmt.Errorf("claim outbox row %d: %w", id, err)
+		}
+		if len(rows) == 0 {
+			// Already sent, already at maxAttempts, or locked by another
+			// relay instance -- nothing for this pass to do.
+			return nil
+		}
+		row := rows[0]
+
+		if perr := r.publish(ctx, row); perr != nil {
+			row.Attempts++
+			row.LastError = perr.Error()
+			if _, uerr := tx.NewUpdate().Model(row).Column("attempts", "last_error").WherePK().Exec(ctx); uerr != nil {
+				return fmt.Errorf("record outbox publish failure for row %d: %w", row.ID, uerr)
+			}
+			return nil
+		}
+		now := time.Now()
+		row.SentAt = &now
+		if _, uerr := tx.NewUpdate().Model(row).Column("sent_at").WherePK().Exec(ctx); uerr != nil {
+			return fmt.Errorf("mark outbox row %d sent: %w", row.ID, uerr)
+		}
+		return nil
+	})
+}
+
+// publish forwards one row to the producer. A numeric Key shards the same
+// way Producer.Publish already does; an empty Key goes to shard 0 so
+// single-stream topics don't need a fake key. Every publish carries a
+// WithIdempotencyKey derived from row.ID, so a row republished after a
+// crash between this call and processOne's mark-sent commit is a no-op on
+// Producer's side instead of a duplicate stream entry.
+func (r *OutboxRelay) publish(ctx context.Context, row *dbx.Outbox) error {
+	values := map[string]any{
+		"topic":   row.Topic,
+		"payload": string(row.Payload),
+	}
+	idempotent := WithIdempotencyKey(fmt.Sprintf("outbox:%d", row.ID))
+	if row.Key == "" {
+		_, err := r.producer.PublishToShard(ctx, 0, values, idempotent)
+		return err
+	}
+	id, err := strconv.ParseInt(row.Key, 10, 64)
+	if err != nil {
+		return fmt.Errorf("outbox row %d: key %q is not a valid shard id: %w", row.ID, row.Key, err)
+	}
+	_, err = r.producer.Publish(ctx, id, values, idempotent)
+	return err
+}
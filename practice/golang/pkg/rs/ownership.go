@@ -0,0 +1,25 @@
+package rs
+
+// OwnerOfShard reports which node, out of totalNodes nodes numbered
+// [0, totalNodes), owns shardID. It reuses the same Hasher the producer
+// shards with so consumer processes agree on ownership without any extra
+// coordination: a process only needs to run Consumers for the shards where
+// OwnerOfShard(h, shard, totalNodes) == myNodeID.
+func OwnerOfShard(h Hasher, shardID, totalNodes int) int {
+	if totalNodes <= 0 {
+		return 0
+	}
+	return h.PartitionID(int64(shardID), totalNodes)
+}
+
+// ShardsOwnedBy returns, out of totalShards shards, the ones myNodeID owns
+// according to h and totalNodes.
+func ShardsOwnedBy(h Hasher, totalShards, totalNodes, myNodeID int) []int {
+	var owned []int
+	for shard := 0; shard < totalShards; shard++ {
+		if OwnerOfShard(h, shard, totalNodes) == myNodeID {
+			owned = append(owned, shard)
+		}
+	}
+	return owned
+}
@@ -7,8 +7,18 @@ import (
 	"time"
 
 	redis "github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// defaultIdempotencyTTL bounds how long a WithIdempotencyKey record is kept
+// when the caller doesn't override it with WithIdempotencyTTL. It only needs
+// to outlive the longest plausible retry window for a single logical
+// publish, not the lifetime of the data itself.
+const defaultIdempotencyTTL = 24 * time.Hour
+
 // Producer publishes messages to sharded Redis Streams: streamBase:{shard}.
 // Shard = hasher.PartitionID(id, parts).
 // Values map will be extended to include the "id" when absent.
@@ -17,6 +27,15 @@ type Producer struct {
 	streamBase string
 	parts      int
 	hasher     Hasher
+
+	idempotencyTTL time.Duration
+	codec          Codec
+
+	tracer          trace.Tracer
+	meter           metric.Meter
+	publishDuration metric.Float64Histogram
+	batchSize       metric.Int64Histogram
+	errorCounter    metric.Int64Counter
 }
 
 type ProducerOption func(*Producer)
@@ -25,14 +44,33 @@ func WithHasher(h Hasher) ProducerOption {
 	return func(p *Producer) { p.hasher = h }
 }
 
-// NewProducer constructs a Producer. parts must be > 0.
+// WithIdempotencyTTL overrides how long WithIdempotencyKey publishes are
+// remembered. Defaults to defaultIdempotencyTTL.
+func WithIdempotencyTTL(d time.Duration) ProducerOption {
+	return func(p *Producer) { p.idempotencyTTL = d }
+}
+
+// WithCodec overrides the Codec PublishEncoded uses. Defaults to
+// NewJSONCodec().
+func WithCodec(codec Codec) ProducerOption {
+	return func(p *Producer) { p.codec = codec }
+}
+
+// NewProducer constructs a Producer. parts must be > 0. By default it
+// observes via otel.Tracer/otel.Meter(instrumentationName), which are no-ops
+// until the caller registers real SDK providers; pass WithTracer/WithMeter to
+// plug in specific ones instead.
 func NewProducer(rdb *redis.Client, streamBase string, parts int, opts ...ProducerOption) *Producer {
 	p := &Producer{
-		rdb:        rdb,
-		streamBase: streamBase,
-		parts:      parts,
-		hasher:     ModHasher{},
+		rdb:            rdb,
+		streamBase:     streamBase,
+		parts:          parts,
+		hasher:         ModHasher{},
+		idempotencyTTL: defaultIdempotencyTTL,
+		codec:          NewJSONCodec(),
 	}
+	p.tracer = otel.Tracer(instrumentationName)
+	p.setMeter(otel.Meter(instrumentationName))
 	for _, opt := range opts {
 		opt(p)
 	}
@@ -50,26 +88,78 @@ func (p *Producer) StreamNameByShard(shard int) string {
 	return fmt.Sprintf("%s:%d", p.streamBase, shard)
 }
 
-// Publish adds a message with partitioned stream based on id.
-func (p *Producer) Publish(ctx context.Context, id int64, values map[string]any) (string, error) {
+// Publish adds a message with partitioned stream based on id. Pass
+// WithIdempotencyKey to make retries of the same logical publish safe.
+func (p *Producer) Publish(ctx context.Context, id int64, values map[string]any, opts ...PublishOption) (string, error) {
 	if values == nil {
 		values = map[string]any{}
 	}
 	if _, ok := values["id"]; !ok {
 		values["id"] = id
 	}
-	stream := p.StreamName(id)
-	return p.rdb.XAdd(ctx, &redis.XAddArgs{Stream: stream, Values: values}).Result()
+	shard := p.hasher.PartitionID(id, p.parts)
+	return p.publish(ctx, shard, values, opts...)
+}
+
+// PublishToShard writes values to the concrete shard stream. Pass
+// WithIdempotencyKey to make retries of the same logical publish safe.
+func (p *Producer) PublishToShard(ctx context.Context, shard int, values map[string]any, opts ...PublishOption) (string, error) {
+	return p.publish(ctx, shard, values, opts...)
+}
+
+// PublishEncoded encodes v with the Producer's Codec (see WithCodec) and
+// publishes it to shard as {"kind": <codec-chosen kind>, "payload": <encoded
+// bytes>, "ts": <unix millis>} -- the field convention HandlerRegistry
+// decodes on the consumer side. Prefer this over building the values map by
+// hand for new message kinds.
+func (p *Producer) PublishEncoded(ctx context.Context, shard int, v any, opts ...PublishOption) (string, error) {
+	payload, kind, err := p.codec.Encode(v)
+	if err != nil {
+		return "", fmt.Errorf("rs: encode %T: %w", v, err)
+	}
+	values := map[string]any{
+		"kind":    kind,
+		"payload": string(payload),
+		"ts":      time.Now().UnixMilli(),
+	}
+	return p.publish(ctx, shard, values, opts...)
 }
 
-// PublishToShard writes values to the concrete shard stream.
-func (p *Producer) PublishToShard(ctx context.Context, shard int, values map[string]any) (string, error) {
+// publish is the instrumented core of Publish/PublishToShard: it wraps the
+// XAdd in an rs.publish span and records rs_publish_duration_seconds /
+// rs_publish_errors_total against shard and stream. When a PublishOption
+// sets an idempotency key, it delegates to publishIdempotent instead of
+// issuing a bare XAdd.
+func (p *Producer) publish(ctx context.Context, shard int, values map[string]any, opts ...PublishOption) (string, error) {
+	var o publishOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.idempotencyKey != "" {
+		return p.publishIdempotent(ctx, shard, values, o.idempotencyKey)
+	}
+
 	stream := p.StreamNameByShard(shard)
-	return p.rdb.XAdd(ctx, &redis.XAddArgs{Stream: stream, Values: values}).Result()
+	ctx, span := p.tracer.Start(ctx, "rs.publish", trace.WithAttributes(
+		attribute.Int("rs.shard", shard),
+		attribute.String("rs.stream", stream),
+	))
+	defer span.End()
+
+	start := time.Now()
+	id, err := p.rdb.XAdd(ctx, &redis.XAddArgs{Stream: stream, Values: values}).Result()
+	p.recordPublish(ctx, shard, stream, time.Since(start), err)
+	if err != nil {
+		span.RecordError(err)
+	}
+	return id, err
 }
 
 // PublishBatchMemberReadTasks groups tasks by shard (hash of MessageID) and publishes
 // one BatchMemberReadTask per shard. Returns a map of shard->streamEntryID.
+// Each shard's publish uses "<batchID>:<shard>" as its idempotency key, so
+// retrying PublishBatchMemberReadTasks for the same batchID (e.g. after the
+// caller's dbx.AfterCommit hook fires twice) republishes nothing.
 func (p *Producer) PublishBatchMemberReadTasks(ctx context.Context, batchID string, tasks []*MemberReadTask) (map[int]string, error) {
 	byShard := make(map[int][]*MemberReadTask)
 	for _, t := range tasks {
@@ -79,6 +169,7 @@ func (p *Producer) PublishBatchMemberReadTasks(ctx context.Context, batchID stri
 	res := make(map[int]string)
 	now := time.Now().UnixMilli()
 	for shard, tsks := range byShard {
+		p.recordBatchSize(ctx, shard, len(tsks))
 		b := &BatchMemberReadTask{ID: batchID, Tasks: tsks}
 		payload, err := json.Marshal(b)
 		if err != nil {
@@ -90,7 +181,7 @@ func (p *Producer) PublishBatchMemberReadTasks(ctx context.Context, batchID stri
 			"payload":  string(payload),
 			"ts":       now,
 		}
-		id, err := p.PublishToShard(ctx, shard, values)
+		id, err := p.PublishToShard(ctx, shard, values, WithIdempotencyKey(fmt.Sprintf("%s:%d", batchID, shard)))
 		if err != nil {
 			return res, err
 		}
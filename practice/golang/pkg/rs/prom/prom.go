@@ -0,0 +1,158 @@
+// Package prom provides a ready-made rs.Observer backed by Prometheus
+// collectors, so a cmd/rs-consumer-style binary gets operational metrics
+// without hand-rolling them against rs.Observer itself.
+package prom
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	redis "github.com/redis/go-redis/v9"
+)
+
+// Observer implements rs.Observer and prometheus.Collector: register it with
+// a prometheus.Registerer (or prometheus.MustRegister it directly), then
+// pass it to rs.WithObserver. Its pending/lag gauges are point-in-time
+// summaries rather than per-event counters, so they're populated separately
+// by WatchPending rather than from the rs.Observer callbacks.
+type Observer struct {
+	stream string
+	group  string
+
+	messages       *prometheus.CounterVec
+	handleDuration prometheus.Histogram
+	pending        prometheus.Gauge
+	lag            prometheus.Gauge
+}
+
+// NewObserver builds an Observer for stream/group -- the concrete
+// streamBase:shard name and consumer group a rs.Consumer runs against.
+func NewObserver(stream, group string) *Observer {
+	return &Observer{
+		stream: stream,
+		group:  group,
+		messages: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "rs_messages_total",
+			Help: "Stream messages processed by rs.Consumer, by result.",
+		}, []string{"stream", "group", "result"}),
+		handleDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:        "rs_handle_duration_seconds",
+			Help:        "Handler latency for rs.Consumer messages.",
+			ConstLabels: prometheus.Labels{"stream": stream, "group": group},
+		}),
+		pending: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name:        "rs_pending_messages",
+			Help:        "Pending (unacked) entries for this stream/group, from XPENDING.",
+			ConstLabels: prometheus.Labels{"stream": stream, "group": group},
+		}),
+		lag: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name:        "rs_stream_lag_ms",
+			Help:        "Milliseconds between the stream's last-generated entry and this group's last-delivered entry.",
+			ConstLabels: prometheus.Labels{"stream": stream, "group": group},
+		}),
+	}
+}
+
+// Describe implements prometheus.Collector.
+func (o *Observer) Describe(ch chan<- *prometheus.Desc) {
+	o.messages.Describe(ch)
+	o.handleDuration.Describe(ch)
+	o.pending.Describe(ch)
+	o.lag.Describe(ch)
+}
+
+// Collect implements prometheus.Collector.
+func (o *Observer) Collect(ch chan<- prometheus.Metric) {
+	o.messages.Collect(ch)
+	o.handleDuration.Collect(ch)
+	o.pending.Collect(ch)
+	o.lag.Collect(ch)
+}
+
+// OnRead implements rs.Observer.
+func (o *Observer) OnRead(n int, lag time.Duration) {
+	if n > 0 {
+		o.messages.WithLabelValues(o.stream, o.group, "read").Add(float64(n))
+	}
+}
+
+// OnHandle implements rs.Observer.
+func (o *Observer) OnHandle(msgID string, err error, dur time.Duration) {
+	o.handleDuration.Observe(dur.Seconds())
+	result := "handled"
+	if err != nil {
+		result = "error"
+	}
+	o.messages.WithLabelValues(o.stream, o.group, result).Inc()
+}
+
+// OnAck implements rs.Observer.
+func (o *Observer) OnAck(msgID string) {
+	o.messages.WithLabelValues(o.stream, o.group, "acked").Inc()
+}
+
+// OnClaim implements rs.Observer.
+func (o *Observer) OnClaim(n int) {
+	if n > 0 {
+		o.messages.WithLabelValues(o.stream, o.group, "claimed").Add(float64(n))
+	}
+}
+
+// WatchPending polls XPENDING and XINFO GROUPS every interval to keep
+// rs_pending_messages and rs_stream_lag_ms current; unlike the other
+// metrics, these are point-in-time summaries rather than events Run can
+// report directly, so they need their own poll loop. It blocks until ctx is
+// done, so run it in its own goroutine alongside Consumer.Run.
+func (o *Observer) WatchPending(ctx context.Context, rdb redis.UniversalClient, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			o.refresh(ctx, rdb)
+		}
+	}
+}
+
+func (o *Observer) refresh(ctx context.Context, rdb redis.UniversalClient) {
+	if summary, err := rdb.XPending(ctx, o.stream, o.group).Result(); err == nil {
+		o.pending.Set(float64(summary.Count))
+	}
+
+	info, err := rdb.XInfoStream(ctx, o.stream).Result()
+	if err != nil {
+		return
+	}
+	groups, err := rdb.XInfoGroups(ctx, o.stream).Result()
+	if err != nil {
+		return
+	}
+	for _, g := range groups {
+		if g.Name != o.group {
+			continue
+		}
+		if lagMs := idMillis(info.LastGeneratedID) - idMillis(g.LastDeliveredID); lagMs > 0 {
+			o.lag.Set(float64(lagMs))
+		}
+		break
+	}
+}
+
+// idMillis extracts the millisecond-timestamp component of a Redis stream
+// ID ("<ms>-<seq>"), returning 0 if id can't be parsed.
+func idMillis(id string) int64 {
+	ms, _, ok := strings.Cut(id, "-")
+	if !ok {
+		return 0
+	}
+	n, err := strconv.ParseInt(ms, 10, 64)
+	if err != nil {
+		return 0
+	}
+	return n
+}
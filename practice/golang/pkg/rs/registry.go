@@ -0,0 +1,82 @@
+package rs
+
+import (
+	"context"
+	"fmt"
+
+	redis "github.com/redis/go-redis/v9"
+)
+
+// HandlerRegistry dispatches stream messages to a typed handler by their
+// "kind" field, decoding "payload" through a Codec. It replaces the
+// per-kind switch + json.Unmarshal that otherwise accumulates in a
+// consumer's message handler as more kinds are added.
+type HandlerRegistry struct {
+	codec    Codec
+	handlers map[string]func(ctx context.Context, msg redis.XMessage, payload []byte) error
+	fallback Handler
+}
+
+// NewHandlerRegistry builds a registry that decodes payloads with codec.
+// A nil codec defaults to NewJSONCodec().
+func NewHandlerRegistry(codec Codec) *HandlerRegistry {
+	if codec == nil {
+		codec = NewJSONCodec()
+	}
+	return &HandlerRegistry{
+		codec:    codec,
+		handlers: make(map[string]func(ctx context.Context, msg redis.XMessage, payload []byte) error),
+	}
+}
+
+// WithFallback sets the Handler used for messages whose kind has no
+// registered handler. Without one, such messages are acked without
+// further processing. Returns r for chaining off NewHandlerRegistry.
+func (r *HandlerRegistry) WithFallback(h Handler) *HandlerRegistry {
+	r.fallback = h
+	return r
+}
+
+// RegisterHandler registers fn to process messages published under kind.
+// If the registry's Codec is a *JSONCodec, T's zero value is also
+// registered as kind's Go type, so Decode knows what to allocate -- callers
+// don't need a separate JSONCodec.RegisterKind call for the common case.
+func RegisterHandler[T any](r *HandlerRegistry, kind string, fn func(ctx context.Context, msg redis.XMessage, v T) error) {
+	if jc, ok := r.codec.(*JSONCodec); ok {
+		var zero T
+		jc.RegisterKind(kind, zero)
+	}
+	r.handlers[kind] = func(ctx context.Context, msg redis.XMessage, payload []byte) error {
+		decoded, err := r.codec.Decode(payload, kind)
+		if err != nil {
+			return fmt.Errorf("rs: decode kind %q for message %s: %w", kind, msg.ID, err)
+		}
+		typed, ok := decoded.(T)
+		if !ok {
+			var zero T
+			return fmt.Errorf("rs: handler for kind %q expects %T, decoded %T", kind, zero, decoded)
+		}
+		return fn(ctx, msg, typed)
+	}
+}
+
+// Handler returns the rs.Handler that dispatches by kind. Pass it to
+// Consumer.Run.
+func (r *HandlerRegistry) Handler() Handler {
+	return func(ctx context.Context, msg redis.XMessage) error {
+		kind, _ := stringField(msg.Values["kind"])
+		h, ok := r.handlers[kind]
+		if !ok {
+			if r.fallback != nil {
+				return r.fallback(ctx, msg)
+			}
+			return nil
+		}
+
+		payload, err := bytesField(msg.Values["payload"])
+		if err != nil {
+			return fmt.Errorf("rs: message %s: %w", msg.ID, err)
+		}
+		return h(ctx, msg, payload)
+	}
+}
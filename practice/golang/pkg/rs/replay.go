@@ -0,0 +1,38 @@
+package rs
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	redis "github.com/redis/go-redis/v9"
+)
+
+// ReplayDLQ republishes every entry currently on dlqStream onto target,
+// stripping the dlq_* bookkeeping fields moveToDeadLetter added. It's meant
+// for operational recovery once whatever caused the dead-lettering is fixed:
+// run it, confirm the replayed entries are processing cleanly on target, then
+// XTRIM or XDEL the replayed range off dlqStream yourself. It returns the
+// number of entries republished.
+func ReplayDLQ(ctx context.Context, rdb redis.UniversalClient, dlqStream, target string) (int, error) {
+	entries, err := rdb.XRange(ctx, dlqStream, "-", "+").Result()
+	if err != nil {
+		return 0, fmt.Errorf("rs: read dead letter stream %s: %w", dlqStream, err)
+	}
+
+	n := 0
+	for _, entry := range entries {
+		values := make(map[string]any, len(entry.Values))
+		for k, v := range entry.Values {
+			if strings.HasPrefix(k, "dlq_") {
+				continue
+			}
+			values[k] = v
+		}
+		if _, err := rdb.XAdd(ctx, &redis.XAddArgs{Stream: target, Values: values}).Result(); err != nil {
+			return n, fmt.Errorf("rs: replay dead letter entry %s: %w", entry.ID, err)
+		}
+		n++
+	}
+	return n, nil
+}